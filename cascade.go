@@ -0,0 +1,222 @@
+package bracelet
+
+import (
+	"sort"
+	"strings"
+)
+
+// The cascade decides which of the declarations that apply to a node wins for
+// each property. Declarations are ordered first by origin and importance:
+//
+//  1. normal user-agent declarations
+//  2. normal author declarations
+//  3. normal declarations in the style attribute
+//  4. important author declarations
+//  5. important declarations in the style attribute
+//  6. important user-agent declarations
+//
+// Within an origin, normal declarations in earlier cascade layers lose to
+// those in later layers, and declarations outside any layer win over all
+// layered ones. Important declarations reverse this order. Ties are broken
+// by specificity, and finally by source order, with later declarations
+// winning.
+
+// cascadeTier is the position of a declaration in the list above.
+type cascadeTier int
+
+var cascadeTiers = struct {
+	UserAgent, Author, Inline, ImportantAuthor, ImportantInline, ImportantUserAgent cascadeTier
+}{0, 1, 2, 3, 4, 5}
+
+// cascadedDeclaration is a declaration that applies to a node, together with
+// everything needed to order it in the cascade.
+type cascadedDeclaration struct {
+	Declaration
+	tier        cascadeTier
+	layer       []int
+	specificity specificity
+}
+
+func tierOf(origin Origin, important bool) cascadeTier {
+	switch {
+	case origin == OriginUserAgent && important:
+		return cascadeTiers.ImportantUserAgent
+	case origin == OriginUserAgent:
+		return cascadeTiers.UserAgent
+	case important:
+		return cascadeTiers.ImportantAuthor
+	default:
+		return cascadeTiers.Author
+	}
+}
+
+// precedes reports whether d loses to other in the cascade, provided other
+// comes later in source order.
+func (d cascadedDeclaration) precedes(other cascadedDeclaration) bool {
+	if d.tier != other.tier {
+		return d.tier < other.tier
+	}
+	if order := compareLayers(d.layer, other.layer); order != 0 {
+		important := d.tier == cascadeTiers.ImportantAuthor || d.tier == cascadeTiers.ImportantUserAgent
+		return (order < 0) != important
+	}
+	return d.specificity.Less(other.specificity)
+}
+
+// properties computes the cascaded value of every property declared for node
+// by the matched rules and its style attribute. Declarations with values that
+// are invalid for their property are ignored, and shorthands are replaced by
+// their longhands, which then cascade like any other declaration.
+func (x *RuleIndex) properties(node *Node, matches []MatchedRule) map[string]string {
+	registry := registryFor(*node)
+	var declarations []cascadedDeclaration
+	for _, match := range matches {
+		for _, declaration := range match.Rule.Declarations {
+			for _, longhand := range x.checkDeclaration(registry, declaration) {
+				declarations = append(declarations, cascadedDeclaration{
+					Declaration: longhand,
+					tier:        tierOf(x.origins[match.rule], declaration.Important),
+					layer:       x.layers[match.rule],
+					specificity: match.Specificity,
+				})
+			}
+		}
+	}
+
+	if inlineStyle, ok := (*node).GetAttributes()["style"]; ok {
+		for _, declaration := range parseInlineDeclarations(inlineStyle) {
+			tier := cascadeTiers.Inline
+			if declaration.Important {
+				tier = cascadeTiers.ImportantInline
+			}
+			for _, longhand := range x.checkDeclaration(registry, declaration) {
+				declarations = append(declarations, cascadedDeclaration{Declaration: longhand, tier: tier})
+			}
+		}
+	}
+
+	sort.SliceStable(declarations, func(i, j int) bool {
+		return declarations[i].precedes(declarations[j])
+	})
+	properties := make(map[string]string, len(declarations))
+	userAgent := make(map[string]string)
+	for _, declaration := range declarations {
+		value := declaration.Value
+		switch {
+		case declaration.tier == cascadeTiers.UserAgent:
+			userAgent[declaration.Name] = value
+		case declaration.tier == cascadeTiers.ImportantUserAgent:
+		case strings.EqualFold(value, keywordRevert):
+			// revert in an author or inline declaration rolls back to the
+			// user-agent origin; without a user-agent value it acts as unset.
+			if reverted, exists := userAgent[declaration.Name]; exists {
+				value = reverted
+			}
+		}
+		properties[declaration.Name] = value
+	}
+	return properties
+}
+
+type checkedValueKey struct {
+	registry    *Registry
+	version     uint64
+	name, value string
+}
+
+// checkDeclaration returns the declarations that replace declaration in the
+// cascade: none if its value is invalid, its longhands with canonical values
+// if it is a shorthand, and otherwise the declaration with its value in
+// canonical form. Results are cached in the index, since the same
+// declarations are checked for many nodes.
+func (x *RuleIndex) checkDeclaration(registry *Registry, declaration Declaration) []Declaration {
+	key := checkedValueKey{registry, registry.propertyVersion(), declaration.Name, declaration.Value}
+	checked, exists := x.checked[key]
+	if !exists {
+		checked, _ = registry.resolveDeclaration(declaration)
+		if x.checked == nil {
+			x.checked = make(map[checkedValueKey][]Declaration)
+		}
+		x.checked[key] = checked
+	}
+	return checked
+}
+
+// parseInlineDeclarations parses the declarations of a style attribute in
+// order, separating !important from their values.
+func parseInlineDeclarations(inlineStyle string) []Declaration {
+	var declarations []Declaration
+	for _, declaration := range strings.Split(inlineStyle, ";") {
+		parts := strings.SplitN(declaration, ":", 2)
+		if len(parts) == 2 {
+			value, important := splitImportant(parts[1])
+			declarations = append(declarations, Declaration{
+				Name:      strings.TrimSpace(parts[0]),
+				Value:     value,
+				Important: important,
+			})
+		}
+	}
+	return declarations
+}
+
+// layerRanks numbers every cascade layer of stylesheets in the order it is
+// first declared. A nested layer such as "base.reset" also declares its
+// parent "base".
+func layerRanks(stylesheets []Stylesheet) map[string]int {
+	ranks := make(map[string]int)
+	declare := func(name string) {
+		if name == "" {
+			return
+		}
+		segments := strings.Split(name, ".")
+		for i := range segments {
+			prefix := strings.Join(segments[:i+1], ".")
+			if _, exists := ranks[prefix]; !exists {
+				ranks[prefix] = len(ranks)
+			}
+		}
+	}
+	for _, stylesheet := range stylesheets {
+		for _, layer := range stylesheet.Layers {
+			declare(layer)
+		}
+		for _, rule := range stylesheet.Rules {
+			declare(rule.Layer)
+		}
+	}
+	return ranks
+}
+
+// layerPath converts a layer name into the ranks of the layer and each of
+// its parents, outermost first.
+func layerPath(name string, ranks map[string]int) []int {
+	if name == "" {
+		return nil
+	}
+	segments := strings.Split(name, ".")
+	path := make([]int, len(segments))
+	for i := range segments {
+		path[i] = ranks[strings.Join(segments[:i+1], ".")]
+	}
+	return path
+}
+
+// compareLayers orders two layer paths for normal declarations, returning a
+// negative number if a loses to b. The rules directly in a layer win over
+// those in its sublayers, just as rules outside any layer win over layered
+// ones.
+func compareLayers(a, b []int) int {
+	for i := 0; ; i++ {
+		switch {
+		case i == len(a) && i == len(b):
+			return 0
+		case i == len(a):
+			return 1
+		case i == len(b):
+			return -1
+		case a[i] != b[i]:
+			return a[i] - b[i]
+		}
+	}
+}
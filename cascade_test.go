@@ -0,0 +1,165 @@
+package bracelet
+
+import (
+	"fmt"
+	"testing"
+)
+
+const cascadeHTML = `<div id="box"><p id="text" class="x">Text</p></div>`
+
+// cascadedColor styles cascadeHTML, with style as the p's style attribute,
+// and returns the color of the p.
+func cascadedColor(t *testing.T, userAgentCSS, authorCSS, style string) string {
+	t.Helper()
+	document := NewDocument()
+	if err := document.ParseHTML(cascadeHTML); err != nil {
+		t.Fatal(err)
+	}
+	text := document.GetElementByID("text")
+	if style != "" {
+		(*text).SetAttribute("style", style)
+	}
+	if userAgentCSS != "" {
+		if err := document.AddUserAgentStylesheet(userAgentCSS); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := document.AddStylesheet(authorCSS); err != nil {
+		t.Fatal(err)
+	}
+	document.Restyle()
+	return (*text).GetProperty("color")
+}
+
+func TestCascade(t *testing.T) {
+	tests := []struct {
+		name                      string
+		userAgent, author, inline string
+		want                      string
+	}{
+		{
+			name:      "author beats user agent regardless of specificity",
+			userAgent: `#text { color: #000001; }`,
+			author:    `p { color: #000002; }`,
+			want:      "#000002",
+		},
+		{
+			name:   "inline beats author",
+			author: `#text.x { color: #000001; }`,
+			inline: "color: #000002",
+			want:   "#000002",
+		},
+		{
+			name:   "important author beats inline",
+			author: `p { color: #000001 !important; }`,
+			inline: "color: #000002",
+			want:   "#000001",
+		},
+		{
+			name:   "important inline beats important author",
+			author: `#text { color: #000001 !important; }`,
+			inline: "color: #000002 !important",
+			want:   "#000002",
+		},
+		{
+			name:      "important user agent beats everything",
+			userAgent: `p { color: #000001 !important; }`,
+			author:    `#text { color: #000002 !important; }`,
+			inline:    "color: #000003 !important",
+			want:      "#000001",
+		},
+		{
+			name:   "specificity beats source order",
+			author: `#text { color: #000001; } p { color: #000002; }`,
+			want:   "#000001",
+		},
+		{
+			name:   "later rule wins at equal specificity",
+			author: `p { color: #000001; } .x { color: #000002; } [id] { color: #000003; }`,
+			want:   "#000003",
+		},
+		{
+			name:   "layers are ordered by their first declaration",
+			author: `@layer base, theme; @layer theme { p { color: #000001; } } @layer base { #text { color: #000002; } }`,
+			want:   "#000001",
+		},
+		{
+			name:   "unlayered rules beat layered ones",
+			author: `p { color: #000001; } @layer base { #text { color: #000002; } }`,
+			want:   "#000001",
+		},
+		{
+			name:   "rules directly in a layer beat its sublayers",
+			author: `@layer base { p { color: #000001; } @layer reset { #text { color: #000002; } } }`,
+			want:   "#000001",
+		},
+		{
+			name:   "nested layer names order like their parents",
+			author: `@layer base.reset, theme; @layer theme { p { color: #000001; } } @layer base.reset { #text { color: #000002; } }`,
+			want:   "#000001",
+		},
+		{
+			name:   "important rules reverse the layer order",
+			author: `@layer base, theme; @layer base { p { color: #000001 !important; } } @layer theme { #text { color: #000002 !important; } }`,
+			want:   "#000001",
+		},
+		{
+			name:   "important layered rules beat important unlayered ones",
+			author: `#text { color: #000001 !important; } @layer base { p { color: #000002 !important; } }`,
+			want:   "#000002",
+		},
+		{
+			name:      "revert rolls back to the user agent",
+			userAgent: `p { color: #000001; }`,
+			author:    `p { color: #000002; } .x { color: revert; }`,
+			want:      "#000001",
+		},
+		{
+			name:      "revert in a style attribute rolls back to the user agent",
+			userAgent: `p { color: #000001; }`,
+			author:    `p { color: #000002; }`,
+			inline:    "color: revert",
+			want:      "#000001",
+		},
+		{
+			name:   "revert without a user agent value inherits",
+			author: `#box { color: #000001; } p { color: #000002; } .x { color: revert; }`,
+			want:   "#000001",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := cascadedColor(t, test.userAgent, test.author, test.inline); got != test.want {
+				t.Errorf("color = %q, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+// TestCascadeSourceOrderIsStable resolves many rules of equal specificity
+// repeatedly, which gave varying results while the cascade was sorted with
+// an unstable sort.
+func TestCascadeSourceOrderIsStable(t *testing.T) {
+	css := ""
+	for i := 1; i <= 20; i++ {
+		css += fmt.Sprintf("p { color: #0000%02d; background-color: #0000%02d; }\n", i, i)
+	}
+	css += `.x { font-weight: bold; } [id] { font-weight: normal; }`
+	for i := 0; i < 50; i++ {
+		parsed, err := ParseHTML(cascadeHTML)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rules, err := ParseCSS(css)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ApplyStylesheet(&parsed, rules)
+		text := byID(t, &parsed, "text")
+		for name, want := range map[string]string{"color": "#000020", "background-color": "#000020", "font-weight": "normal"} {
+			if got := (*text).GetProperty(name); got != want {
+				t.Fatalf("run %d: %s = %q, want %s", i, name, got, want)
+			}
+		}
+	}
+}
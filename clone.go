@@ -0,0 +1,81 @@
+package bracelet
+
+import (
+	"reflect"
+	"slices"
+)
+
+// Cloner can be implemented by custom node types that hold state which must
+// not be shared between copies, such as ImgNode's canvas. Nodes that do not
+// implement it are copied field by field.
+type Cloner interface {
+	// CloneNode returns a copy of the node without a parent or children.
+	CloneNode() Node
+}
+
+// Clone returns a copy of node with its own classes, attributes and
+// properties. If deep is true, the node's descendants are cloned as well and
+// attached to the copy. The returned node has no parent.
+func Clone(node *Node, deep bool) *Node {
+	if node == nil {
+		return nil
+	}
+	clone := cloneNode(*node)
+	clonePtr := &clone
+	if deep {
+		for _, child := range (*node).GetChildren() {
+			_ = AppendChild(clonePtr, Clone(child, true))
+		}
+	}
+	return clonePtr
+}
+
+// cloneNode makes a shallow copy of a single node, using its Cloner
+// implementation if it has one.
+func cloneNode(node Node) Node {
+	if cloner, ok := node.(Cloner); ok {
+		clone := ensureInitialized(cloner.CloneNode())
+		clone.SetParent(nil)
+		clone.SetChildren([]*Node{})
+		return clone
+	}
+
+	clone := node
+	if value := reflect.ValueOf(node); value.Kind() == reflect.Pointer && !value.IsNil() {
+		copied := reflect.New(value.Elem().Type())
+		copied.Elem().Set(value.Elem())
+		clone = copied.Interface().(Node)
+	}
+	if embedded, ok := clone.(embeddedElement); ok {
+		element := embedded.element()
+		*element = CloneElement(*element)
+	}
+	clone.SetClasses(append([]string{}, node.GetClasses()...))
+	clone.SetAttributes(node.GetAttributes())
+	clone.SetProperties(node.GetProperties())
+	clone.SetParent(nil)
+	clone.SetChildren([]*Node{})
+	return ensureInitialized(clone)
+}
+
+// CloneElement returns a copy of an Element with its own classes, attributes
+// and properties, and without a parent, children or cached rendering. Custom
+// node types that embed Element can use it in their CloneNode method to copy
+// the embedded Element.
+func CloneElement(e Element) Element {
+	clone := e
+	clone.Classes = append([]string{}, e.Classes...)
+	clone.Attributes = make(map[string]string, len(e.Attributes))
+	for key, value := range e.Attributes {
+		clone.Attributes[key] = value
+	}
+	clone.Properties = make(map[string]string, len(e.Properties))
+	for key, value := range e.Properties {
+		clone.Properties[key] = value
+	}
+	clone.cascaded = slices.Clone(e.cascaded)
+	clone.Parent = nil
+	clone.Children = []*Node{}
+	clone.cache = renderCache{}
+	return clone
+}
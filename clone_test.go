@@ -0,0 +1,104 @@
+package bracelet
+
+import (
+	"slices"
+	"testing"
+)
+
+const cloneHTML = `<ul id="list" class="rows" data-kind="list"><li class="row">A</li><li class="row">B</li></ul>`
+
+func TestClone(t *testing.T) {
+	parsed, err := ParseHTML(cloneHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseCSS(`li { color: #ff0000; margin-left: 1; }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStylesheet(&parsed, rules)
+	list := byID(t, &parsed, "list")
+	want := (*list).Serve()
+
+	clone := Clone(list, true)
+	if (*clone).GetParent() != nil {
+		t.Error("the clone has a parent")
+	}
+	checkParents(t, clone)
+	if got := (*clone).Serve(); got != want {
+		t.Errorf("clone renders\n%s\nwant\n%s", got, want)
+	}
+	if got := len(FindAll(*clone, "li.row")); got != 2 {
+		t.Errorf("clone has %d rows, want 2", got)
+	}
+
+	// Changing the clone leaves the original alone.
+	(*clone).AddClass("copy")
+	(*clone).SetAttribute("data-kind", "copy")
+	row := Find(*clone, "li")
+	(*row).SetProperty("color", "#00ff00")
+	(*(*row).GetChildren()[0]).SetContent("Changed")
+	if slices.Contains((*list).GetClasses(), "copy") || (*list).GetAttribute("data-kind") != "list" {
+		t.Error("changing the clone's classes or attributes changed the original")
+	}
+	original := Find(parsed, "li")
+	if got := (*original).GetProperty("color"); got != "#ff0000" {
+		t.Errorf("original color = %q after changing the clone, want #ff0000", got)
+	}
+	if got := (*list).Serve(); got != want {
+		t.Errorf("original renders\n%s\nafter changing the clone, want\n%s", got, want)
+	}
+	if (*clone).Serve() == want {
+		t.Error("the clone kept its cached rendering after its content changed")
+	}
+
+	shallow := Clone(list, false)
+	if len((*shallow).GetChildren()) != 0 {
+		t.Error("a shallow clone has children")
+	}
+	if (*shallow).GetID() != "list" || !slices.Equal((*shallow).GetClasses(), []string{"rows"}) {
+		t.Error("a shallow clone did not copy the ID and classes")
+	}
+}
+
+// counterNode is a custom node with state that copies must not share.
+type counterNode struct {
+	Element
+	count *int
+}
+
+func (n *counterNode) CloneNode() Node {
+	count := *n.count
+	return &counterNode{Element: CloneElement(n.Element), count: &count}
+}
+
+func TestCloneUsesCloner(t *testing.T) {
+	count := 1
+	node := Node(&counterNode{Element: NewElement("counter"), count: &count})
+	(*node.(*counterNode)).AddClass("a")
+	child := Node(&counterNode{Element: NewElement("counter"), count: &count})
+	if err := AppendChild(&node, &child); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := Clone(&node, true)
+	copied, ok := (*clone).(*counterNode)
+	if !ok {
+		t.Fatalf("clone is a %T, want *counterNode", *clone)
+	}
+	*copied.count = 5
+	if count != 1 {
+		t.Error("the clone shares its counter with the original")
+	}
+	copied.AddClass("b")
+	if slices.Contains(node.GetClasses(), "b") {
+		t.Error("the clone shares its classes with the original")
+	}
+	children := copied.GetChildren()
+	if len(children) != 1 || !sameNode((*children[0]).GetParent(), clone) {
+		t.Error("the clone's child is not attached to it")
+	}
+	if _, ok := (*children[0]).(*counterNode); !ok {
+		t.Errorf("the clone's child is a %T, want *counterNode", *children[0])
+	}
+}
@@ -0,0 +1,87 @@
+package bracelet
+
+import (
+	"sort"
+	"strconv"
+)
+
+// ComputedValues maps property names to the values in effect for a node.
+type ComputedValues map[string]string
+
+// Int returns the value of an integer property such as margin-left, or 0 if
+// the property has no value or is not an integer.
+func (c ComputedValues) Int(name string) int {
+	value, _ := strconv.Atoi(c[name])
+	return value
+}
+
+// ComputedStyle returns the value in effect for node of every longhand
+// property known to its registry, along with any other properties set on it.
+// Shorthands set on the node are expanded into longhands, values that are
+// invalid for their property are ignored, the CSS-wide keywords are resolved,
+// inherited properties the node does not set take their parent's computed
+// value, and all others take their initial value. Properties without a value
+// are left out.
+func ComputedStyle(node *Node) ComputedValues {
+	var parent ComputedValues
+	if p := (*node).GetParent(); p != nil {
+		parent = ComputedStyle(p)
+	}
+	return registryFor(*node).computeValues((*node).GetTag(), (*node).GetProperties(), parent)
+}
+
+// computeValues computes the values of properties for a node with tag whose
+// parent has the computed values parent.
+func (r *Registry) computeValues(tag string, properties map[string]string, parent ComputedValues) ComputedValues {
+	properties = r.resolveVariables(properties, nil, parent)
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	declared := make(map[string]string, len(properties))
+	for _, name := range names {
+		declarations, err := r.resolveDeclaration(Declaration{Name: name, Value: properties[name]})
+		if err != nil {
+			continue
+		}
+		for _, declaration := range declarations {
+			// A longhand set on the node wins over the same longhand
+			// expanded from a shorthand, as it does when rendering.
+			if _, direct := properties[declaration.Name]; direct && declaration.Name != name {
+				continue
+			}
+			declared[declaration.Name] = declaration.Value
+		}
+	}
+
+	computed := make(ComputedValues)
+	for _, name := range r.longhandNames() {
+		metadata, _ := r.propertyMetadata(name)
+		metadata = metadata.forTag(tag)
+		value, exists := declared[name]
+		if !exists {
+			value = keywordUnset
+		}
+		if resolved, exists := resolveKeyword(value, metadata, parent[name]); exists {
+			computed[name] = resolved
+		}
+	}
+	for name, value := range declared {
+		if _, known := computed[name]; known {
+			continue
+		}
+		metadata, _ := r.propertyMetadata(name)
+		metadata = metadata.forTag(tag)
+		if resolved, exists := resolveKeyword(value, metadata, parent[name]); exists {
+			computed[name] = resolved
+		}
+	}
+	for name, value := range parent {
+		if _, declared := declared[name]; !declared && isCustomProperty(name) {
+			computed[name] = value
+		}
+	}
+	return computed
+}
@@ -0,0 +1,71 @@
+package bracelet
+
+import "testing"
+
+func TestShorthandsExpandAtCascade(t *testing.T) {
+	document := restyledDocument(t, `<p id="text">Text</p>`, `p { margin: 1 2 3; margin-left: 4; padding: 2; }`)
+	text := document.GetElementByID("text")
+	if (*text).HasProperty("margin") || (*text).HasProperty("padding") {
+		t.Errorf("shorthands were kept in the cascaded properties %v", (*text).GetProperties())
+	}
+	want := map[string]string{
+		"margin-top": "1", "margin-right": "2", "margin-bottom": "3", "margin-left": "4",
+		"padding-top": "2", "padding-right": "2", "padding-bottom": "2", "padding-left": "2",
+	}
+	for name, value := range want {
+		if got := (*text).GetProperty(name); got != value {
+			t.Errorf("%s = %q, want %s", name, got, value)
+		}
+	}
+}
+
+func TestComputedStyle(t *testing.T) {
+	document := restyledDocument(t, `<div id="box"><p id="text">Text</p></div>`,
+		`div { color: #FF0000; margin: 2; font-weight: bold; text-decoration: underline; }
+		p { margin-top: inherit; text-decoration: inherit; width: abc; }`)
+	text := document.GetElementByID("text")
+	computed := ComputedStyle(text)
+
+	want := map[string]string{
+		"color":           "#ff0000",   // inherited
+		"font-weight":     "bold",      // inherited
+		"font-style":      "normal",    // initial value of an inherited property
+		"margin-top":      "2",         // explicitly inherited
+		"margin-left":     "0",         // initial value, not inherited
+		"text-decoration": "underline", // explicitly inherited
+		"word-spacing":    "1",         // initial value
+		"text-align":      "left",      // initial value
+	}
+	for name, value := range want {
+		if got := computed[name]; got != value {
+			t.Errorf("computed %s = %q, want %s", name, got, value)
+		}
+	}
+	for _, name := range []string{"width", "margin", "border-top", "background-color"} {
+		if value, exists := computed[name]; exists {
+			t.Errorf("computed %s = %q, want no value", name, value)
+		}
+	}
+	if got := computed.Int("margin-top"); got != 2 {
+		t.Errorf("Int(margin-top) = %d, want 2", got)
+	}
+}
+
+func TestComputedStyleOfUnstyledNode(t *testing.T) {
+	element := NewElement("p")
+	element.SetProperty("margin", "1 2")
+	element.SetProperty("margin-right", "5")
+	element.SetProperty("color", "unset")
+	var node Node = &element
+
+	computed := ComputedStyle(&node)
+	want := map[string]string{"margin-top": "1", "margin-right": "5", "margin-bottom": "1", "margin-left": "2"}
+	for name, value := range want {
+		if got := computed[name]; got != value {
+			t.Errorf("computed %s = %q, want %s", name, got, value)
+		}
+	}
+	if value, exists := computed["color"]; exists {
+		t.Errorf("computed color = %q, want no value", value)
+	}
+}
@@ -2,8 +2,9 @@ package bracelet
 
 import (
 	"fmt"
-	"sort"
+	"maps"
 	"strings"
+	"sync/atomic"
 
 	"github.com/gorilla/css/scanner"
 )
@@ -11,30 +12,79 @@ import (
 type Declaration struct {
 	Name  string
 	Value string
+	// Important is set when the value was followed by !important, which is
+	// not included in Value.
+	Important bool
 }
 
 type Rule struct {
 	Selectors    []Selector
 	Declarations []Declaration
+	// Layer is the dot-separated name of the cascade layer the rule was
+	// declared in with @layer, or empty if it is not in a layer.
+	Layer string
 }
 
 type Stylesheet struct {
 	Rules []Rule
+	// Origin decides the precedence of the stylesheet in the cascade.
+	Origin Origin
+	// Layers lists the cascade layers in the order they were declared,
+	// including layers named by @layer statements without a block.
+	Layers []string
 }
 
+// Origin identifies where a stylesheet comes from. Declarations from the
+// author origin override those from the user-agent origin, except that
+// important user-agent declarations override everything else.
+type Origin int
+
+const (
+	// OriginAuthor is the origin of the stylesheets of an application.
+	OriginAuthor Origin = iota
+	// OriginUserAgent is the origin of default styles, such as those of a
+	// component library, that applications are expected to override.
+	OriginUserAgent
+)
+
+// anonymousLayers numbers anonymous @layer blocks so that each one is a
+// distinct layer.
+var anonymousLayers atomic.Uint64
+
 // ParseCSS parses a CSS string and returns a slice of Rules.
 // It handles selectors, declarations, and nested rules.
 func ParseCSS(cssContent string) ([]Rule, error) {
+	stylesheet, err := ParseStylesheet(cssContent)
+	return stylesheet.Rules, err
+}
+
+// ParseStylesheet parses a CSS string into an author Stylesheet. Unlike
+// ParseCSS it keeps the order of layers declared by @layer statements
+// such as "@layer base, components;".
+func ParseStylesheet(cssContent string) (Stylesheet, error) {
 
-	stylesheet := []Rule{}
+	stylesheet := Stylesheet{Rules: []Rule{}}
 	s := scanner.New(cssContent)
 
-	var State = struct{ Selector, Declaration, Value int }{0, 1, 2}
+	var State = struct{ Selector, Declaration, Value, Layer int }{0, 1, 2, 3}
 	currentState := State.Selector
 	currentRule := Rule{}
 	currentSelector := ""
 	currentDeclaration := Declaration{}
 	declarationString := ""
+	layerPrelude := ""
+	var layers []string
+
+	declareLayer := func(name string) string {
+		full := strings.Join(append(append([]string{}, layers...), name), ".")
+		for _, declared := range stylesheet.Layers {
+			if declared == full {
+				return full
+			}
+		}
+		stylesheet.Layers = append(stylesheet.Layers, full)
+		return full
+	}
 
 	for {
 		token := s.Next()
@@ -43,36 +93,71 @@ func ParseCSS(cssContent string) ([]Rule, error) {
 			break
 		}
 
+		if currentState == State.Selector && token.Type == scanner.TokenAtKeyword && strings.EqualFold(token.Value, "@layer") {
+			currentState = State.Layer
+			layerPrelude = ""
+			continue
+		}
+		if currentState == State.Layer {
+			switch token.Value {
+			case "{":
+				name := strings.TrimSpace(layerPrelude)
+				if name == "" {
+					name = fmt.Sprintf("<anonymous-%d>", anonymousLayers.Add(1))
+				}
+				declareLayer(name)
+				layers = append(layers, name)
+				currentState = State.Selector
+			case ";":
+				for _, name := range strings.Split(layerPrelude, ",") {
+					if name = strings.TrimSpace(name); name != "" {
+						declareLayer(name)
+					}
+				}
+				currentState = State.Selector
+			default:
+				layerPrelude += token.Value
+			}
+			continue
+		}
+
 		switch token.Value {
 		case "{":
 			if currentState == State.Selector {
-				selector, err := parseSelector(strings.TrimSpace(currentSelector))
+				selectors, err := parseSelectorList(strings.TrimSpace(currentSelector))
 				if err == nil {
-					currentRule.Selectors = append(currentRule.Selectors, selector)
+					currentRule.Selectors = append(currentRule.Selectors, selectors...)
 				} else {
 					fmt.Printf("Error parsing selector: %v\n", err)
 					return stylesheet, err
 				}
+				currentRule.Layer = strings.Join(layers, ".")
 				currentSelector = ""
 				currentState = State.Declaration
 			}
 		case "}":
+			if currentState == State.Selector && len(layers) > 0 {
+				layers = layers[:len(layers)-1]
+			}
 			if len(currentRule.Selectors) > 0 && len(currentRule.Declarations) > 0 {
-				stylesheet = append(stylesheet, currentRule)
+				stylesheet.Rules = append(stylesheet.Rules, currentRule)
 			}
 			currentRule = Rule{}
 			currentState = State.Selector
 		case ":":
-			if currentState == State.Declaration {
+			switch currentState {
+			case State.Selector:
+				currentSelector += token.Value
+			case State.Declaration:
 				currentDeclaration.Name = strings.TrimSpace(currentDeclaration.Name)
 				currentState = State.Value
 				declarationString = ""
-			} else {
+			default:
 				declarationString += token.Value
 			}
 		case ";":
 			if currentState == State.Value {
-				currentDeclaration.Value = strings.TrimSpace(declarationString)
+				currentDeclaration.Value, currentDeclaration.Important = splitImportant(declarationString)
 				currentRule.Declarations = append(currentRule.Declarations, currentDeclaration)
 				currentDeclaration = Declaration{}
 				declarationString = ""
@@ -93,6 +178,17 @@ func ParseCSS(cssContent string) ([]Rule, error) {
 	return stylesheet, nil
 }
 
+// splitImportant trims a declaration value and removes a trailing
+// !important, reporting whether it was present.
+func splitImportant(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	bang := strings.LastIndex(value, "!")
+	if bang < 0 || !strings.EqualFold(strings.TrimSpace(value[bang+1:]), "important") {
+		return value, false
+	}
+	return strings.TrimSpace(value[:bang]), true
+}
+
 // ParseInlineStyle parses an inline style string and returns a PropertyMap.
 // The inline style string should be in the format "property: value; property: value;".
 func ParseInlineStyle(inlineStyle string) map[string]string {
@@ -110,29 +206,15 @@ func ParseInlineStyle(inlineStyle string) map[string]string {
 }
 
 // DetermineProperties calculates the final set of CSS properties for a given node,
-// taking into account the stylesheet rules and any inline styles.
+// taking into account the stylesheet rules and any inline styles. Inherited
+// properties are taken from the current properties of the node's parent.
+//
+// Each rule is tested against the node in turn. To style many nodes with the
+// same rules, build a RuleIndex once and use its DetermineProperties method,
+// or use ApplyStylesheet or a Document, which index the rules themselves.
 func DetermineProperties(node *Node, stylesheet []Rule) map[string]string {
-	properties := make(map[string]string)
-
-	rules := matchingRules(node, stylesheet)
-	sort.Slice(rules, func(i, j int) bool {
-		return rules[i].Specificity.Less(rules[j].Specificity)
-	})
-	for _, matchedRule := range rules {
-		for _, declaration := range matchedRule.Rule.Declarations {
-			properties[declaration.Name] = declaration.Value
-		}
-	}
-
-	attributes := (*node).GetAttributes()
-	if inlineStyle, ok := attributes["style"]; ok {
-		inlineProperties := ParseInlineStyle(inlineStyle)
-		for property, value := range inlineProperties {
-			properties[property] = value
-		}
-	}
-
-	return properties
+	rules := collectRules([]Stylesheet{{Rules: stylesheet}})
+	return computeProperties(node, rules.properties(node, rules.scan(node)))
 }
 
 // ApplyStylesheet applies the given stylesheet to the node and all its descendants.
@@ -143,10 +225,57 @@ func ApplyStylesheet(node *Node, stylesheet []Rule) {
 		return
 	}
 
-	properties := DetermineProperties(node, stylesheet)
+	ApplyStylesheets(node, Stylesheet{Rules: stylesheet})
+}
+
+// ApplyStylesheets applies several stylesheets to the node and all its
+// descendants, cascading their rules according to their origins and layers.
+func ApplyStylesheets(node *Node, stylesheets ...Stylesheet) {
+	if node == nil {
+		fmt.Println("Warning: nil node passed to ApplyStylesheets")
+		return
+	}
+
+	newStylesheetIndex(stylesheets).cascade(node, func(node *Node, properties map[string]string) {
+		(*node).AddProperties(properties)
+		storeStyle(node)
+	})
+}
+
+// restyleNode sets the properties the cascade computed for a single node.
+// Properties set by the previous restyle that no longer apply are removed,
+// while properties set by other means are kept unless the cascade overrides
+// them.
+func restyleNode(node *Node, properties map[string]string) {
+	embedded, tracked := (*node).(embeddedElement)
+	if !tracked {
+		(*node).AddProperties(properties)
+		storeStyle(node)
+		return
+	}
+	element := embedded.element()
+	previous := maps.Clone(element.Properties)
+
+	element.cache.held, element.restyling = true, true
+	for _, key := range element.cascaded {
+		if _, exists := properties[key]; !exists {
+			(*node).RemoveProperty(key)
+		}
+	}
+	retained := maps.Clone(element.Properties)
 	(*node).AddProperties(properties)
+	element.cache.held, element.restyling = false, false
 
-	for _, child := range (*node).GetChildren() {
-		ApplyStylesheet(child, stylesheet)
+	cascaded := make([]string, 0, len(properties))
+	for key := range element.Properties {
+		_, set := properties[key]
+		if _, kept := retained[key]; set || !kept {
+			cascaded = append(cascaded, key)
+		}
+	}
+	element.cascaded = cascaded
+	storeStyle(node)
+	if !maps.Equal(previous, element.Properties) {
+		element.renderChanged()
 	}
 }
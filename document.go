@@ -0,0 +1,397 @@
+package bracelet
+
+import "slices"
+
+// Document owns a node tree together with the stylesheets applied to it and
+// the Registry of custom nodes and property functions used to build and
+// render it. Independent documents in one process can use different
+// registries without affecting each other.
+type Document struct {
+	Root        *Node
+	Stylesheets []Stylesheet
+
+	registry *Registry
+	ids      map[string]*Node
+	idsStale bool
+
+	styleAll bool
+	dirty    []styleInvalidation
+	index    *RuleIndex
+}
+
+// styleInvalidation records a node whose style may be out of date. A
+// structural invalidation means the node's children changed, and a content
+// invalidation that the node's content changed, which only matters to
+// stylesheets that use :empty.
+type styleInvalidation struct {
+	node       *Node
+	structural bool
+	content    bool
+}
+
+// NewDocument creates an empty Document whose registry is a snapshot of
+// DefaultRegistry.
+func NewDocument() *Document {
+	return NewDocumentWithRegistry(DefaultRegistry.Snapshot())
+}
+
+// NewDocumentWithRegistry creates an empty Document that uses registry. A
+// frozen registry can be shared by many documents.
+func NewDocumentWithRegistry(registry *Registry) *Document {
+	return &Document{
+		registry: registry,
+		ids:      make(map[string]*Node),
+	}
+}
+
+// Registry returns the registry used by the document.
+func (d *Document) Registry() *Registry {
+	return d.registry
+}
+
+// RegisterNode registers a custom node type for a tag in this document only.
+func (d *Document) RegisterNode(tag string, node Node) error {
+	return d.registry.RegisterNode(tag, node)
+}
+
+// RegisterPropertyFunction registers a property function for this document only.
+func (d *Document) RegisterPropertyFunction(name string, function func(string) PropertyFunction) error {
+	return d.registry.RegisterPropertyFunction(name, function)
+}
+
+// RegisterPropertyMetadata declares the metadata of a property for this document only.
+func (d *Document) RegisterPropertyMetadata(name string, metadata PropertyMetadata) error {
+	return d.registry.RegisterPropertyMetadata(name, metadata)
+}
+
+// RegisterProperty registers a property for this document only.
+func (d *Document) RegisterProperty(descriptor PropertyDescriptor) error {
+	return d.registry.RegisterProperty(descriptor)
+}
+
+// CreateNode creates a node for a tag using this document's node registry.
+// The node belongs to the document but is not attached to its tree.
+func (d *Document) CreateNode(tag string) *Node {
+	node := createNodeFrom(d.registry, tag)
+	setOwnerDocument(&node, d)
+	return &node
+}
+
+// ParseHTML parses an HTML string using this document's node registry and
+// makes the resulting tree the document's root.
+func (d *Document) ParseHTML(htmlContent string) error {
+	root, err := parseHTML(htmlContent, d.registry)
+	if err != nil {
+		return err
+	}
+	d.SetRoot(root)
+	return nil
+}
+
+// SetRoot makes node the root of the document, adopting its subtree.
+func (d *Document) SetRoot(node *Node) {
+	d.Root = node
+	adoptNode(node, d)
+	d.idsStale = true
+	d.styleAll = true
+	d.dirty = nil
+}
+
+// AddStylesheet parses CSS and attaches the resulting stylesheet to the
+// document. The tree is not restyled until Restyle is called.
+func (d *Document) AddStylesheet(cssContent string) error {
+	return d.addStylesheet(cssContent, OriginAuthor)
+}
+
+// AddUserAgentStylesheet parses CSS and attaches it as a user-agent
+// stylesheet, whose normal declarations are overridden by those of author
+// stylesheets regardless of specificity.
+func (d *Document) AddUserAgentStylesheet(cssContent string) error {
+	return d.addStylesheet(cssContent, OriginUserAgent)
+}
+
+func (d *Document) addStylesheet(cssContent string, origin Origin) error {
+	stylesheet, err := ParseStylesheet(cssContent)
+	if err != nil {
+		return err
+	}
+	stylesheet.Origin = origin
+	d.Stylesheets = append(d.Stylesheets, stylesheet)
+	d.styleAll = true
+	d.index = nil
+	return nil
+}
+
+// InvalidateStyle marks the whole tree as needing a restyle. It is only needed
+// after changing Stylesheets directly or setting node fields without using
+// their setters; the setters and the tree mutation functions invalidate the
+// affected nodes themselves.
+func (d *Document) InvalidateStyle() {
+	d.styleAll = true
+	d.dirty = nil
+	d.index = nil
+}
+
+// Rules returns the rules of all attached stylesheets in the order they were added.
+func (d *Document) Rules() []Rule {
+	var rules []Rule
+	for _, stylesheet := range d.Stylesheets {
+		rules = append(rules, stylesheet.Rules...)
+	}
+	return rules
+}
+
+// Restyle applies the attached stylesheets to the parts of the tree that may
+// have changed since the last call. Changes to a node's tag, ID, classes or
+// attributes restyle the node, its descendants and its following siblings,
+// which sibling combinators may depend on, as well as its preceding siblings
+// if a stylesheet uses :last-child, :only-child, :nth-last-child or their
+// -of-type variants. Changes to content restyle the parent in the same way
+// if a stylesheet uses :empty. Adding or removing children
+// restyles the parent's subtree. The whole tree is restyled after SetRoot,
+// AddStylesheet or InvalidateStyle, and whenever a stylesheet uses :has(),
+// since a change can then affect the node's ancestors.
+//
+// Properties applied by a previous Restyle that no longer match are removed.
+func (d *Document) Restyle() {
+	all, dirty := d.styleAll, d.dirty
+	d.styleAll, d.dirty = false, nil
+	if d.Root == nil {
+		return
+	}
+	if d.index == nil {
+		d.index = newStylesheetIndex(d.Stylesheets)
+	}
+	if !d.index.emptiness {
+		dirty = slices.DeleteFunc(dirty, func(invalidation styleInvalidation) bool {
+			return invalidation.content
+		})
+	}
+	if all || (d.index.relational && len(dirty) > 0) {
+		restyleSubtree(d.Root, d.index)
+		return
+	}
+
+	var roots []*Node
+	for _, invalidation := range dirty {
+		node := invalidation.node
+		if invalidation.content {
+			// The node's own :empty state and its parent's may change.
+			node = d.restyleParent(node)
+		}
+		if !invalidation.structural && d.index.siblingFilters {
+			node = d.restyleParent(node)
+		}
+		if !isInclusiveAncestor(d.Root, node) {
+			continue
+		}
+		roots = append(roots, node)
+		// The siblings of the root are not part of the document.
+		if !invalidation.structural && !sameNode(node, d.Root) {
+			for sibling := NextSibling(node); sibling != nil; sibling = NextSibling(sibling) {
+				roots = append(roots, sibling)
+			}
+			if d.index.backwardSiblings {
+				for sibling := PreviousSibling(node); sibling != nil; sibling = PreviousSibling(sibling) {
+					roots = append(roots, sibling)
+				}
+			}
+		}
+	}
+	for i, node := range roots {
+		if !restyleCovered(roots, i) {
+			restyleSubtree(node, d.index)
+		}
+	}
+}
+
+// restyleParent returns the parent of node, or node itself if it is the
+// document's root or has no parent, so that a restyle never starts above
+// the root.
+func (d *Document) restyleParent(node *Node) *Node {
+	if parent := (*node).GetParent(); parent != nil && !sameNode(node, d.Root) {
+		return parent
+	}
+	return node
+}
+
+// restyleCovered reports whether roots[i] is restyled as part of another
+// root's subtree, or is a duplicate of an earlier root.
+func restyleCovered(roots []*Node, i int) bool {
+	for j, other := range roots {
+		switch {
+		case j == i:
+		case sameNode(other, roots[i]):
+			if j < i {
+				return true
+			}
+		case isInclusiveAncestor(other, roots[i]):
+			return true
+		}
+	}
+	return false
+}
+
+// restyleSubtree restyles node and its descendants, parents before children
+// so that inherited properties are up to date.
+func restyleSubtree(node *Node, index *RuleIndex) {
+	index.cascade(node, restyleNode)
+}
+
+// maxDirtyNodes is the number of invalidations recorded before the whole tree
+// is marked for restyling instead, which bounds the memory held by a document
+// that keeps changing without being restyled.
+const maxDirtyNodes = 256
+
+// invalidateContent records that the content of node changed.
+func (d *Document) invalidateContent(node *Node) {
+	d.invalidate(styleInvalidation{node: node, content: true})
+}
+
+// invalidateStyle records that node may need restyling. A structural change
+// means the node's children were added, removed or reordered.
+func (d *Document) invalidateStyle(node *Node, structural bool) {
+	d.invalidate(styleInvalidation{node: node, structural: structural})
+}
+
+func (d *Document) invalidate(invalidation styleInvalidation) {
+	if d.styleAll || invalidation.node == nil {
+		return
+	}
+	for _, recorded := range d.dirty {
+		if recorded.structural == invalidation.structural && recorded.content == invalidation.content &&
+			sameNode(recorded.node, invalidation.node) {
+			return
+		}
+	}
+	if len(d.dirty) >= maxDirtyNodes {
+		d.styleAll, d.dirty = true, nil
+		return
+	}
+	d.dirty = append(d.dirty, invalidation)
+}
+
+// Diagnostics reports the declarations in the document's stylesheets and
+// style attributes that are ignored because their values are invalid.
+func (d *Document) Diagnostics() []Diagnostic {
+	diagnostics := d.registry.Validate(d.Rules())
+	if d.Root == nil {
+		return diagnostics
+	}
+	Walk(d.Root, func(node *Node) WalkAction {
+		if inlineStyle, ok := (*node).GetAttributes()["style"]; ok {
+			for _, declaration := range parseInlineDeclarations(inlineStyle) {
+				if _, err := d.registry.parseValue(declaration.Name, declaration.Value); err != nil {
+					diagnostics = append(diagnostics, Diagnostic{
+						Property: declaration.Name,
+						Value:    declaration.Value,
+						Node:     node,
+						Err:      err,
+					})
+				}
+			}
+		}
+		return WalkContinue
+	})
+	return diagnostics
+}
+
+// Render renders the document's tree into a styled string.
+func (d *Document) Render() string {
+	if d.Root == nil {
+		return ""
+	}
+	return (*d.Root).Serve()
+}
+
+// Query returns the first node in the document matching selector, or nil if
+// none does. Invalid selectors are reported as a *SelectorError.
+func (d *Document) Query(selector string) (*Node, error) {
+	compiled, err := CompileSelector(selector)
+	if err != nil || d.Root == nil {
+		return nil, err
+	}
+	return compiled.find(d.Root), nil
+}
+
+// QueryAll returns all nodes in the document matching selector in document
+// order. Invalid selectors are reported as a *SelectorError.
+func (d *Document) QueryAll(selector string) ([]*Node, error) {
+	compiled, err := CompileSelector(selector)
+	if err != nil || d.Root == nil {
+		return nil, err
+	}
+	return compiled.findAll(d.Root), nil
+}
+
+// GetElementByID returns the first node in document order with the given ID,
+// or nil if there is none. Lookups use an index that is rebuilt only after
+// the tree or node IDs change.
+func (d *Document) GetElementByID(id string) *Node {
+	if d.idsStale {
+		d.rebuildIDIndex()
+	}
+	return d.ids[id]
+}
+
+func (d *Document) rebuildIDIndex() {
+	d.ids = make(map[string]*Node)
+	Walk(d.Root, func(node *Node) WalkAction {
+		if id := (*node).GetID(); id != "" {
+			if _, exists := d.ids[id]; !exists {
+				d.ids[id] = node
+			}
+		}
+		return WalkContinue
+	})
+	d.idsStale = false
+}
+
+// invalidateIDs marks the ID index as out of date.
+func (d *Document) invalidateIDs() {
+	d.idsStale = true
+}
+
+// documentOwner is implemented by nodes that know which Document they belong
+// to. Element implements it, so all nodes that embed Element do as well.
+type documentOwner interface {
+	OwnerDocument() *Document
+	setOwnerDocument(*Document)
+}
+
+// OwnerDocument returns the Document that node belongs to, or nil if it was
+// not created by or adopted into a Document.
+func OwnerDocument(node *Node) *Document {
+	if node == nil {
+		return nil
+	}
+	if owner, ok := (*node).(documentOwner); ok {
+		return owner.OwnerDocument()
+	}
+	return nil
+}
+
+func setOwnerDocument(node *Node, d *Document) {
+	if owner, ok := (*node).(documentOwner); ok {
+		owner.setOwnerDocument(d)
+	}
+}
+
+// adoptNode makes d the owner of node and all its descendants.
+func adoptNode(node *Node, d *Document) {
+	Walk(node, func(n *Node) WalkAction {
+		setOwnerDocument(n, d)
+		return WalkContinue
+	})
+}
+
+// registryFor returns the registry that applies to a node: its document's if
+// it has one, and DefaultRegistry otherwise.
+func registryFor(node Node) *Registry {
+	if owner, ok := node.(documentOwner); ok {
+		if d := owner.OwnerDocument(); d != nil {
+			return d.registry
+		}
+	}
+	return DefaultRegistry
+}
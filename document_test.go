@@ -0,0 +1,148 @@
+package bracelet
+
+import (
+	"maps"
+	"testing"
+)
+
+func restyledDocument(t *testing.T, html, css string) *Document {
+	t.Helper()
+	document := NewDocument()
+	if err := document.ParseHTML(html); err != nil {
+		t.Fatal(err)
+	}
+	if err := document.AddStylesheet(css); err != nil {
+		t.Fatal(err)
+	}
+	document.Restyle()
+	return document
+}
+
+// checkFullRestyle fails the test if restyling the whole document changes
+// the properties of any node, which means an incremental Restyle missed it.
+func checkFullRestyle(t *testing.T, document *Document) {
+	t.Helper()
+	before := make(map[*Node]map[string]string)
+	Walk(document.Root, func(node *Node) WalkAction {
+		before[node] = maps.Clone((*node).GetProperties())
+		return WalkContinue
+	})
+	document.InvalidateStyle()
+	document.Restyle()
+	Walk(document.Root, func(node *Node) WalkAction {
+		if after := (*node).GetProperties(); !maps.Equal(before[node], after) {
+			t.Errorf("<%s id=%q> had %v after Restyle, want %v", (*node).GetTag(), (*node).GetID(), before[node], after)
+		}
+		return WalkContinue
+	})
+}
+
+func TestRestyleAfterContentChangeWithEmpty(t *testing.T) {
+	document := restyledDocument(t, `<div><p id="p">text</p><em id="after">x</em></div>`,
+		`p:empty { color: #ff0000; } p:empty + em { font-weight: bold; }`)
+	p := document.GetElementByID("p")
+	text := (*p).GetChildren()[0]
+
+	(*text).SetContent("   ")
+	document.Restyle()
+	if got := (*p).GetProperty("color"); got != "#ff0000" {
+		t.Errorf("color of p with whitespace content = %q, want #ff0000", got)
+	}
+	if got := (*document.GetElementByID("after")).GetProperty("font-weight"); got != "bold" {
+		t.Errorf("font-weight of the em after an empty p = %q, want bold", got)
+	}
+	checkFullRestyle(t, document)
+
+	(*text).SetContent("again")
+	document.Restyle()
+	if got := (*p).GetProperty("color"); got != "" {
+		t.Errorf("color of p with text content = %q, want none", got)
+	}
+	checkFullRestyle(t, document)
+}
+
+func TestRestylePrecedingSiblingsWithLastOfType(t *testing.T) {
+	document := restyledDocument(t, `<div><span id="first">a</span><span id="last">b</span></div>`,
+		`span:last-of-type { color: #ff0000; }`)
+	first, last := document.GetElementByID("first"), document.GetElementByID("last")
+	if got := (*last).GetProperty("color"); got != "#ff0000" {
+		t.Fatalf("color of the last span = %q, want #ff0000", got)
+	}
+
+	(*last).SetTag("em")
+	document.Restyle()
+	if got := (*first).GetProperty("color"); got != "#ff0000" {
+		t.Errorf("color of the span that became last of type = %q, want #ff0000", got)
+	}
+	if got := (*last).GetProperty("color"); got != "" {
+		t.Errorf("color of the former last span = %q, want none", got)
+	}
+	checkFullRestyle(t, document)
+}
+
+func TestRestyleRootWithSiblingFilters(t *testing.T) {
+	document := restyledDocument(t, `<ul id="list"><li class="x">a</li><li>b</li></ul>`,
+		`.on { color: #ff0000; } li:nth-child(1 of .x) { color: #00ff00; }`)
+
+	(*document.Root).AddClass("on")
+	document.Restyle()
+	if got := (*document.Root).GetProperty("color"); got != "#ff0000" {
+		t.Errorf("color of the root = %q, want #ff0000", got)
+	}
+	if got := (*document.GetElementByID("list")).GetProperty("color"); got != "#ff0000" {
+		t.Errorf("color inherited from the root = %q, want #ff0000", got)
+	}
+	checkFullRestyle(t, document)
+}
+
+func TestInvalidationsAreBounded(t *testing.T) {
+	document := restyledDocument(t, `<ul id="list"><li id="item">a</li></ul>`, `li { color: #ff0000; }`)
+	item := document.GetElementByID("item")
+	text := (*item).GetChildren()[0]
+
+	for i := 0; i < 1000; i++ {
+		(*text).SetContent("b")
+		(*item).AddClass("on")
+		document.Render()
+	}
+	if len(document.dirty) != 2 {
+		t.Errorf("repeated changes to the same nodes recorded %d invalidations, want 2", len(document.dirty))
+	}
+
+	list := document.GetElementByID("list")
+	for i := 0; i < maxDirtyNodes; i++ {
+		if err := AppendChild(list, document.CreateNode("li")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	document.Restyle()
+	for _, child := range (*list).GetChildren() {
+		(*child).AddClass("on")
+	}
+	if !document.styleAll || len(document.dirty) != 0 {
+		t.Errorf("after %d changes styleAll = %v with %d invalidations, want the whole tree marked instead",
+			maxDirtyNodes+1, document.styleAll, len(document.dirty))
+	}
+	document.Restyle()
+	checkFullRestyle(t, document)
+}
+
+func TestQuery(t *testing.T) {
+	document := restyledDocument(t, `<ul id="list"><li id="a" class="x">a</li><li id="b">b</li></ul>`, `li { color: #ff0000; }`)
+	if node, err := document.Query("body"); err != nil || node != document.Root {
+		t.Errorf("Query(body) = %v, %v, want the document root", node, err)
+	}
+	if node, err := document.Query("li:not(.x)"); err != nil || node != document.GetElementByID("b") {
+		t.Errorf("Query(li:not(.x)) = %v, %v, want the second li", node, err)
+	}
+	nodes, err := document.QueryAll(":root, li")
+	if err != nil || len(nodes) != 3 || nodes[0] != document.Root {
+		t.Errorf("QueryAll(:root, li) = %v, %v, want the root and both li", nodes, err)
+	}
+	if _, err := document.Query("li["); err == nil {
+		t.Error("Query accepted an invalid selector")
+	}
+	if _, err := document.QueryAll("li["); err == nil {
+		t.Error("QueryAll accepted an invalid selector")
+	}
+}
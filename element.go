@@ -1,6 +1,10 @@
 package bracelet
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 type Element struct {
 	Tag        string
@@ -12,11 +16,20 @@ type Element struct {
 	Properties map[string]string
 	Parent     *Node
 	Children   []*Node
+
+	document *Document
+	cascaded []string
+	cache    renderCache
+
+	// restyling is set while a restyle replaces the element's properties,
+	// which must not invalidate its style again.
+	restyling bool
 }
 
 // Serve renders the Element and its children into a styled string.
-// It applies all CSS properties to the Element's content and recursively
-// renders child Elements. The method handles both leaf nodes (Elements with
+// It applies all CSS properties to the Element's content, in the order given
+// by their PropertyPhase and then by name, and recursively renders child
+// Elements. The method handles both leaf nodes (Elements with
 // no children) and container nodes (Elements with children).
 //
 // For leaf nodes, it applies styling to the Element's content.
@@ -24,15 +37,18 @@ type Element struct {
 // according to the specified layout direction (horizontal or vertical).
 //
 // The final output is a string that represents the fully styled Element,
-// ready for display in a terminal interface.
+// ready for display in a terminal interface. It is cached and returned as is
+// by later calls until the Element or one of its descendants changes. Serve
+// may be called from several goroutines at once, as long as none of them
+// changes the tree.
 func (e *Element) Serve() string {
-	style := lipgloss.NewStyle()
-	content := e.GetContent()
-	for key, value := range e.GetProperties() {
-		if function, exists := PropertyFunctions[key]; exists {
-			content, style = function(value)(content, style)
-		}
+	registry := registryFor(e)
+	if output, hit := e.cache.lookup(registry); hit {
+		return output
 	}
+	version := registry.propertyVersion()
+
+	content, style := registry.applyProperties(e.GetContent(), e.GetProperties())
 	children := e.GetChildren()
 	if len(children) != 0 {
 		var contents []string
@@ -49,7 +65,9 @@ func (e *Element) Serve() string {
 			content = lipgloss.JoinHorizontal(style.GetAlignHorizontal(), contents...)
 		}
 	}
-	return style.Render(content)
+	output := style.Render(content)
+	e.cache.store(registry, version, output)
+	return output
 }
 
 // NewElement creates a new Element with all fields properly initialized
@@ -120,22 +138,48 @@ func (n *Element) GetParent() *Node { return n.Parent }
 func (n *Element) GetChildren() []*Node { return n.Children }
 
 // SetTag sets the HTML tag name of the node.
-func (n *Element) SetTag(tag string) { n.Tag = tag }
+func (n *Element) SetTag(tag string) {
+	n.Tag = tag
+	n.styleChanged()
+}
 
 // SetID sets the ID attribute of the node.
-func (n *Element) SetID(id string) { n.ID = id }
+func (n *Element) SetID(id string) {
+	n.ID = id
+	if n.document != nil {
+		n.document.invalidateIDs()
+	}
+	n.styleChanged()
+}
 
 // SetStyle sets the lipgloss.Style for the node, which determines its appearance.
 func (n *Element) SetStyle(style lipgloss.Style) { n.Style = style }
 
 // SetContent sets the text content of the node.
-func (n *Element) SetContent(content string) { n.Content = content }
+func (n *Element) SetContent(content string) {
+	if n.Content == content {
+		return
+	}
+	n.Content = content
+	n.renderChanged()
+	if n.document != nil {
+		if node := n.selfNode(); node != nil {
+			n.document.invalidateContent(node)
+		}
+	}
+}
 
 // SetClasses sets the CSS class names for the node.
-func (n *Element) SetClasses(classes []string) { n.Classes = classes }
+func (n *Element) SetClasses(classes []string) {
+	n.Classes = classes
+	n.styleChanged()
+}
 
 // AddClass adds one more CSS class names for the node.
-func (n *Element) AddClass(classes ...string) { n.Classes = append(n.Classes, classes...) }
+func (n *Element) AddClass(classes ...string) {
+	n.Classes = append(n.Classes, classes...)
+	n.styleChanged()
+}
 
 // RemoveClass removes one or more classes from the node's Classes slice.
 func (e *Element) RemoveClass(classes ...string) {
@@ -153,6 +197,7 @@ func (e *Element) RemoveClass(classes ...string) {
 		}
 	}
 	e.Classes = newClasses
+	e.styleChanged()
 }
 
 // HasClass returns true if the node has the specified class, false otherwise.
@@ -169,11 +214,15 @@ func (e *Element) HasClass(class string) bool {
 func (n *Element) SetAttributes(attributes map[string]string) {
 	n.Attributes = map[string]string{}
 	n.AddAttributes(attributes)
+	n.styleChanged()
+	n.renderChanged()
 }
 
 // SetAttribute sets a single attribute on the node. If the attribute already exists, its value is replaced.
 func (n *Element) SetAttribute(attr string, value string) {
 	n.Attributes[attr] = value
+	n.styleChanged()
+	n.renderChanged()
 }
 
 // AddAttributes sets attributes for the node, adding to and updating existing attributes.
@@ -188,6 +237,8 @@ func (e *Element) RemoveAttribute(keys ...string) {
 	for _, key := range keys {
 		delete(e.Attributes, key)
 	}
+	e.styleChanged()
+	e.renderChanged()
 }
 
 // HasAttribute returns true if the node has the specified attribute, false otherwise.
@@ -204,7 +255,12 @@ func (n *Element) SetProperties(properties map[string]string) {
 
 // SetProperty sets a single CSS property on the node. If the property already exists, its value is replaced.
 func (n *Element) SetProperty(prop string, value string) {
+	if current, exists := n.Properties[prop]; exists && current == value {
+		return
+	}
 	n.Properties[prop] = value
+	n.renderChanged()
+	n.variableChanged(prop)
 }
 
 // AddProperties sets CSS properties for the node, adding to and updating existing properties.
@@ -217,7 +273,11 @@ func (n *Element) AddProperties(properties map[string]string) {
 // RemoveProperty removes one or more properties from the node's Properties map.
 func (e *Element) RemoveProperty(keys ...string) {
 	for _, key := range keys {
-		delete(e.Properties, key)
+		if _, exists := e.Properties[key]; exists {
+			delete(e.Properties, key)
+			e.renderChanged()
+			e.variableChanged(key)
+		}
 	}
 }
 
@@ -231,10 +291,16 @@ func (e *Element) HasProperty(key string) bool {
 func (n *Element) SetParent(parent *Node) { n.Parent = parent }
 
 // SetChildren sets all child nodes of this node, replacing any existing children.
-func (n *Element) SetChildren(children []*Node) { n.Children = children }
+func (n *Element) SetChildren(children []*Node) {
+	n.Children = children
+	n.renderChanged()
+}
 
 // AddChild adds a new child node to this node.
-func (n *Element) AddChild(child *Node) { n.Children = append(n.Children, child) }
+func (n *Element) AddChild(child *Node) {
+	n.Children = append(n.Children, child)
+	n.renderChanged()
+}
 
 // SetChild sets a child node at a specific index. If the index is out of range, the child is appended.
 func (n *Element) SetChild(index int, child *Node) {
@@ -247,4 +313,62 @@ func (n *Element) SetChild(index int, child *Node) {
 		nodePtr := Node(n)
 		(*child).SetParent(&nodePtr)
 	}
+	n.renderChanged()
+}
+
+// OwnerDocument returns the Document the node belongs to, or nil if it has none.
+func (n *Element) OwnerDocument() *Document { return n.document }
+
+func (n *Element) setOwnerDocument(d *Document) { n.document = d }
+
+// embeddedElement is implemented by Element and so by every node type that
+// embeds it, giving access to the underlying Element.
+type embeddedElement interface {
+	element() *Element
+}
+
+func (n *Element) element() *Element { return n }
+
+// selfNode returns the pointer through which the element is held by its
+// parent, or the document root pointer if the element is the root.
+func (n *Element) selfNode() *Node {
+	isSelf := func(node *Node) bool {
+		embedded, ok := (*node).(embeddedElement)
+		return ok && embedded.element() == n
+	}
+	if n.Parent != nil {
+		for _, sibling := range (*n.Parent).GetChildren() {
+			if isSelf(sibling) {
+				return sibling
+			}
+		}
+		return nil
+	}
+	if n.document != nil && n.document.Root != nil && isSelf(n.document.Root) {
+		return n.document.Root
+	}
+	return nil
+}
+
+// variableChanged restyles the element and its descendants when a custom
+// property they may refer to with var() is set or removed, unless a restyle
+// is already replacing the element's properties. A value set other than by a
+// restyle is the element's own, which the cascade no longer replaces.
+func (n *Element) variableChanged(prop string) {
+	if !isCustomProperty(prop) || n.restyling {
+		return
+	}
+	n.cascaded = slices.DeleteFunc(n.cascaded, func(name string) bool { return name == prop })
+	n.styleChanged()
+}
+
+// styleChanged tells the owning document that selectors may now match the
+// element differently.
+func (n *Element) styleChanged() {
+	if n.document == nil {
+		return
+	}
+	if node := n.selfNode(); node != nil {
+		n.document.invalidateStyle(node, false)
+	}
 }
@@ -0,0 +1,75 @@
+package bracelet
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestServeIsDeterministic renders properties whose effects overlap, which
+// gave different output from run to run while they were applied in map
+// order.
+func TestServeIsDeterministic(t *testing.T) {
+	properties := map[string]string{
+		"margin":         "1 2",
+		"margin-left":    "4",
+		"indent":         "2",
+		"border":         "rounded",
+		"border-top":     "false",
+		"padding":        "0 1",
+		"padding-right":  "3",
+		"text-transform": "uppercase",
+		"word-spacing":   "2",
+	}
+	render := func() string {
+		element := NewElement("p")
+		element.SetContent("some text")
+		element.AddProperties(properties)
+		return element.Serve()
+	}
+
+	want := render()
+	for i := 0; i < 50; i++ {
+		if got := render(); got != want {
+			t.Fatalf("render %d differs:\n%s\nwant:\n%s", i, got, want)
+		}
+	}
+
+	lines := strings.Split(want, "\n")
+	// One blank line of top margin, no top border, then the text with the
+	// left margin plus indent before the left border.
+	if len(lines) < 2 || strings.TrimSpace(lines[0]) != "" {
+		t.Fatalf("output does not start with a top margin:\n%s", want)
+	}
+	if text := lines[1]; !strings.HasPrefix(text, strings.Repeat(" ", 6)+"│ SOME  TEXT   │") {
+		t.Errorf("text line = %q, want 6 columns of margin, a border, padding and uppercase text", text)
+	}
+}
+
+func TestApplicationOrder(t *testing.T) {
+	properties := map[string]string{
+		"custom":           "",
+		"indent":           "",
+		"width":            "",
+		"margin-left":      "",
+		"margin":           "",
+		"color":            "",
+		"background-color": "",
+		"word-spacing":     "",
+		"text-transform":   "",
+	}
+	want := []string{
+		"text-transform", "word-spacing", // content
+		"background-color", "color", // text
+		"margin",      // shorthands
+		"margin-left", // longhands
+		"width",       // layout
+		"indent",      // derived
+		"custom",      // default
+	}
+	for i := 0; i < 10; i++ {
+		if got := DefaultRegistry.applicationOrder(properties); !slices.Equal(got, want) {
+			t.Fatalf("application order %v, want %v", got, want)
+		}
+	}
+}
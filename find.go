@@ -1,55 +1,106 @@
 package bracelet
 
-// Find searches for a single node matching the given CSS selector, starting from the root node.
-// It returns a pointer to the first matching Node, or nil if no match is found.
-func Find(root Node, selector string) *Node {
-	parsedSelector, err := parseSelector(selector)
+// CompiledSelector is a parsed selector or selector list that can be reused
+// across queries without being parsed again. It implements Selector, so
+// Matches reports whether a single node matches.
+type CompiledSelector struct {
+	Selector
+}
+
+// CompileSelector parses a selector or comma-separated selector list. Syntax
+// errors are returned as a *SelectorError describing where parsing failed.
+func CompileSelector(selector string) (*CompiledSelector, error) {
+	selectors, err := parseSelectorList(selector)
 	if err != nil {
-		return nil
+		return nil, err
 	}
-
-	var results []*Node
-	results = traverseNodes(&root, parsedSelector, results, false)
-	if len(results) == 1 {
-		return results[0]
+	if len(selectors) == 1 {
+		return &CompiledSelector{selectors[0]}, nil
 	}
-	return nil
+	return &CompiledSelector{selectorList(selectors)}, nil
 }
 
-// FindAll searches for all nodes matching the given CSS selector, starting from the root node.
-// It returns a slice of pointers to all matching Nodes.
-func FindAll(root Node, selector string) []*Node {
-	parsedSelector, err := parseSelector(selector)
+// MustCompileSelector is like CompileSelector but panics if the selector
+// cannot be parsed. It simplifies initialization of package-level selectors.
+func MustCompileSelector(selector string) *CompiledSelector {
+	compiled, err := CompileSelector(selector)
 	if err != nil {
-		return []*Node{}
+		panic("bracelet: MustCompileSelector: " + err.Error())
 	}
+	return compiled
+}
+
+// FindIn searches for a single node matching the selector, starting from the root node.
+// It returns a pointer to the first matching Node in document order, or nil if no match is found.
+func (s *CompiledSelector) FindIn(root Node) *Node {
+	return s.find(&root)
+}
+
+// FindAllIn searches for all nodes matching the selector, starting from the root node.
+// It returns a slice of pointers to all matching Nodes in document order.
+func (s *CompiledSelector) FindAllIn(root Node) []*Node {
+	return s.findAll(&root)
+}
+
+// find returns the first node in the tree of root matching the selector.
+// Unlike FindIn, it returns root itself rather than a copy of the pointer if
+// root matches.
+func (s *CompiledSelector) find(root *Node) *Node {
+	var result *Node
+	Walk(root, func(node *Node) WalkAction {
+		if s.Matches(node) {
+			result = node
+			return WalkStop
+		}
+		return WalkContinue
+	})
+	return result
+}
 
+// findAll returns all nodes in the tree of root matching the selector.
+func (s *CompiledSelector) findAll(root *Node) []*Node {
 	var results []*Node
-	results = traverseNodes(&root, parsedSelector, results, true)
+	Walk(root, func(node *Node) WalkAction {
+		if s.Matches(node) {
+			results = append(results, node)
+		}
+		return WalkContinue
+	})
 	return results
 }
 
-func traverseNodes(node *Node, selector Selector, results []*Node, all bool) []*Node {
-	rule := Rule{
-		Selectors: []Selector{selector},
+// Closest returns node or its nearest ancestor that matches the selector, or
+// nil if none does.
+func (s *CompiledSelector) Closest(node *Node) *Node {
+	if node == nil {
+		return nil
 	}
-
-	if match := matchRule(node, rule); match != nil {
-		results = append(results, node)
-		if !all {
-			return results
+	for current := node; current != nil; current = (*current).GetParent() {
+		if s.Matches(current) {
+			return current
 		}
 	}
+	return nil
+}
 
-	for _, child := range (*node).GetChildren() {
-		results = traverseNodes(child, selector, results, all)
-		if !all && len(results) > 0 {
-			return results
-		}
-	}
-	if len(results) == 0 {
+// Find searches for a single node matching the given CSS selector, starting from the root node.
+// It returns a pointer to the first matching Node, or nil if no match is found or
+// the selector is invalid. Use CompileSelector to detect invalid selectors.
+func Find(root Node, selector string) *Node {
+	compiled, err := CompileSelector(selector)
+	if err != nil {
 		return nil
 	}
+	return compiled.FindIn(root)
+}
 
-	return results
+// FindAll searches for all nodes matching the given CSS selector, starting from the root node.
+// It returns a slice of pointers to all matching Nodes, which is empty if the selector
+// is invalid. Use CompileSelector to detect invalid selectors.
+func FindAll(root Node, selector string) []*Node {
+	compiled, err := CompileSelector(selector)
+	if err != nil {
+		return []*Node{}
+	}
+	return compiled.FindAllIn(root)
 }
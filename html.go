@@ -10,20 +10,31 @@ import (
 // ParseHTML parses an HTML string and returns the root Node of the resulting tree.
 // It handles nested elements, attributes, and text nodes.
 func ParseHTML(htmlContent string) (Node, error) {
+	root, err := parseHTML(htmlContent, DefaultRegistry)
+	if err != nil {
+		return nil, err
+	}
+	return *root, nil
+}
+
+// parseHTML parses an HTML string, creating nodes from the given registry, and
+// returns the body node. The body is detached from the html element the
+// parser wraps it in, so that it is the root of the tree and matches :root.
+func parseHTML(htmlContent string, registry *Registry) (*Node, error) {
 	reader := strings.NewReader(htmlContent)
 	doc, err := html.Parse(reader)
 	if err != nil {
 		return nil, fmt.Errorf("html.Parse error: %v", err)
 	}
 
-	var buildTree func(*html.Node, int, int) (Node, error)
-	buildTree = func(n *html.Node, totalSiblings, childIndex int) (Node, error) {
+	var buildTree func(*html.Node, int, int) (*Node, error)
+	buildTree = func(n *html.Node, totalSiblings, childIndex int) (*Node, error) {
 		if n == nil {
 			return nil, fmt.Errorf("nil html.Node encountered")
 		}
 
 		if n.Type == html.ElementNode {
-			node := createNode(n.Data)
+			node := createNodeFrom(registry, n.Data)
 			if node == nil {
 				return nil, fmt.Errorf("createNode returned nil for tag %s", n.Data)
 			}
@@ -42,18 +53,19 @@ func ParseHTML(htmlContent string) (Node, error) {
 				childCount++
 			}
 
+			nodePtr := &node
 			for c, i := n.FirstChild, 0; c != nil; c, i = c.NextSibling, i+1 {
 				child, err := buildTree(c, childCount, i)
 				if err != nil {
 					return nil, fmt.Errorf("error building child node: %v", err)
 				}
 				if child != nil {
-					childPtr := &child
-					node.AddChild(childPtr)
-					child.SetParent(&node)
+					if err := AppendChild(nodePtr, child); err != nil {
+						return nil, fmt.Errorf("error appending child node: %v", err)
+					}
 				}
 			}
-			return node, nil
+			return nodePtr, nil
 		} else if n.Type == html.TextNode {
 			var textContent string
 
@@ -65,7 +77,7 @@ func ParseHTML(htmlContent string) (Node, error) {
 			}
 
 			if textContent != "" {
-				textNode := createNode("text")
+				textNode := createNodeFrom(registry, "text")
 
 				// Remove duplicate spaces
 				for strings.Contains(textContent, "  ") {
@@ -76,7 +88,7 @@ func ParseHTML(htmlContent string) (Node, error) {
 					return nil, fmt.Errorf("createNode returned nil for text element")
 				}
 				textNode.SetContent(textContent)
-				return textNode, nil
+				return &textNode, nil
 			}
 			return nil, nil
 		} else if n.Type == html.DocumentNode {
@@ -100,7 +112,19 @@ func ParseHTML(htmlContent string) (Node, error) {
 	if rootNode == nil {
 		return nil, fmt.Errorf("root node is nil after parsing")
 	}
-	return *Find(rootNode, "body"), nil
+	var body *Node
+	Walk(rootNode, func(node *Node) WalkAction {
+		if tagsEqual((*node).GetTag(), "body") {
+			body = node
+			return WalkStop
+		}
+		return WalkContinue
+	})
+	if body == nil {
+		return nil, fmt.Errorf("body node not found after parsing")
+	}
+	Detach(body)
+	return body, nil
 }
 
 // PrintStyledHTML prints a styled representation of the HTML tree to the console.
@@ -0,0 +1,158 @@
+package bracelet
+
+import (
+	"slices"
+	"strings"
+)
+
+// PropertyMetadata describes how the cascade treats a property that is not
+// declared for a node, and what the CSS-wide keywords mean for it.
+type PropertyMetadata struct {
+	// Inherited properties take the value of the parent node when they are
+	// not declared for a node.
+	Inherited bool
+	// Initial is the value used for "initial", and for "unset" on properties
+	// that are not inherited. An empty Initial means the property has no
+	// value and is removed from the node.
+	Initial string
+	// InheritedBy lists the tags of nodes that inherit the property even
+	// though it is not Inherited, such as text nodes, which have no box of
+	// their own and take the box properties of the element holding them.
+	InheritedBy []string
+}
+
+// forTag returns the metadata that applies to nodes with tag, which treats
+// the property as inherited if tag is listed in InheritedBy.
+func (m PropertyMetadata) forTag(tag string) PropertyMetadata {
+	if !m.Inherited && slices.Contains(m.InheritedBy, strings.ToLower(tag)) {
+		m.Inherited = true
+	}
+	return m
+}
+
+// PropertyDefaults holds the metadata of the built-in properties and backs
+// DefaultRegistry. Writing to it directly is not safe while other goroutines
+// style trees; use RegisterPropertyMetadata instead.
+//
+// Unlike in CSS, indent and text-indent are not inherited, since they are
+// applied as a left margin and would accumulate on nested nodes, while text
+// nodes inherit the background color, width and height of their parent.
+var PropertyDefaults = map[string]PropertyMetadata{
+	"color":            {Inherited: true},
+	"background-color": {InheritedBy: []string{"text"}},
+	"font-weight":      {Inherited: true, Initial: "normal"},
+	"text-transform":   {Inherited: true, Initial: "none"},
+	"font-style":       {Inherited: true, Initial: "normal"},
+	"text-decoration":  {Initial: "none"},
+	"margin":           {Initial: "0"},
+	"margin-top":       {Initial: "0"},
+	"margin-bottom":    {Initial: "0"},
+	"margin-left":      {Initial: "0"},
+	"margin-right":     {Initial: "0"},
+	"padding":          {Initial: "0"},
+	"padding-top":      {Initial: "0"},
+	"padding-bottom":   {Initial: "0"},
+	"padding-left":     {Initial: "0"},
+	"padding-right":    {Initial: "0"},
+	"border":           {},
+	"border-top":       {},
+	"border-bottom":    {},
+	"border-left":      {},
+	"border-right":     {},
+	"width":            {InheritedBy: []string{"text"}},
+	"height":           {InheritedBy: []string{"text"}},
+	"text-align":       {Inherited: true, Initial: "left"},
+	"vertical-align":   {Initial: "top"},
+	"indent":           {Initial: "0"},
+	"text-indent":      {Initial: "0"},
+	"word-spacing":     {Inherited: true, Initial: "1"},
+	"direction":        {},
+}
+
+// The CSS-wide keywords, which can be the value of any property.
+const (
+	keywordInherit = "inherit"
+	keywordInitial = "initial"
+	keywordUnset   = "unset"
+	keywordRevert  = "revert"
+)
+
+// computeProperties resolves the CSS-wide keywords in the cascaded
+// properties of node and adds the inherited properties it does not declare,
+// taking their values from its parent's properties.
+func computeProperties(node *Node, cascaded map[string]string) map[string]string {
+	registry := registryFor(*node)
+	tag := (*node).GetTag()
+	var parentProperties map[string]string
+	if parent := (*node).GetParent(); parent != nil {
+		parentProperties = (*parent).GetProperties()
+	}
+	own := ownVariables(node)
+	cascaded = registry.resolveVariables(cascaded, own, parentProperties)
+
+	computed := make(map[string]string, len(cascaded))
+	for name, value := range cascaded {
+		metadata, _ := registry.propertyMetadata(name)
+		if resolved, exists := resolveKeyword(value, metadata.forTag(tag), parentProperties[name]); exists {
+			computed[name] = resolved
+		}
+	}
+
+	for name, value := range parentProperties {
+		_, declared := cascaded[name]
+		if _, set := own[name]; declared || set {
+			continue
+		}
+		if metadata, _ := registry.propertyMetadata(name); metadata.forTag(tag).Inherited {
+			computed[name] = value
+		}
+	}
+	return computed
+}
+
+// ownVariables returns the custom properties set on node other than by the
+// cascade, such as with SetProperty, which var() can refer to as well. Only
+// elements in a Document keep track of which properties the cascade set, so
+// other nodes have none.
+func ownVariables(node *Node) map[string]string {
+	embedded, tracked := (*node).(embeddedElement)
+	if !tracked || embedded.element().document == nil {
+		return nil
+	}
+	fromCascade := embedded.element().cascaded
+	var own map[string]string
+	for name, value := range (*node).GetProperties() {
+		if !isCustomProperty(name) || slices.Contains(fromCascade, name) {
+			continue
+		}
+		if own == nil {
+			own = make(map[string]string)
+		}
+		own[name] = value
+	}
+	return own
+}
+
+// resolveKeyword resolves a value that may be a CSS-wide keyword, given the
+// metadata of its property and the parent's value, which is empty if the
+// parent has none. It reports false if the property ends up without a value.
+func resolveKeyword(value string, metadata PropertyMetadata, parentValue string) (string, bool) {
+	keyword := strings.ToLower(strings.TrimSpace(value))
+	if keyword == keywordUnset || keyword == keywordRevert {
+		keyword = keywordInitial
+		if metadata.Inherited {
+			keyword = keywordInherit
+		}
+	}
+	switch keyword {
+	case keywordInherit:
+		if parentValue != "" {
+			return parentValue, true
+		}
+		return metadata.Initial, metadata.Initial != ""
+	case keywordInitial:
+		return metadata.Initial, metadata.Initial != ""
+	default:
+		return value, true
+	}
+}
@@ -0,0 +1,69 @@
+package bracelet
+
+import "testing"
+
+const textBoxHTML = `<div id="box">Text</div>`
+
+const textBoxCSS = `#box { background-color: #ff0000; width: 10; height: 2; color: #00ff00; margin-left: 1; }`
+
+func textChild(t *testing.T, document *Document) *Node {
+	t.Helper()
+	children := (*document.GetElementByID("box")).GetChildren()
+	if len(children) != 1 || (*children[0]).GetTag() != "text" {
+		t.Fatalf("box has children %v, want a single text node", children)
+	}
+	return children[0]
+}
+
+func TestTextNodesInheritBoxProperties(t *testing.T) {
+	document := restyledDocument(t, textBoxHTML, textBoxCSS)
+	text := textChild(t, document)
+
+	want := map[string]string{"background-color": "#ff0000", "width": "10", "height": "2", "color": "#00ff00"}
+	for name, value := range want {
+		if got := (*text).GetProperty(name); got != value {
+			t.Errorf("text node %s = %q, want %q", name, got, value)
+		}
+	}
+	if (*text).HasProperty("margin-left") {
+		t.Error("text node inherited margin-left")
+	}
+
+	box := document.GetElementByID("box")
+	(*box).SetAttribute("style", "background-color: unset")
+	document.Restyle()
+	if (*text).HasProperty("background-color") {
+		t.Errorf("text node kept background-color %q after its parent lost it", (*text).GetProperty("background-color"))
+	}
+}
+
+func TestInheritedByFollowsRegisteredMetadata(t *testing.T) {
+	document := NewDocument()
+	if err := document.RegisterPropertyMetadata("width", PropertyMetadata{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := document.RegisterPropertyMetadata("margin-left", PropertyMetadata{Initial: "0", InheritedBy: []string{"text"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := document.ParseHTML(textBoxHTML); err != nil {
+		t.Fatal(err)
+	}
+	if err := document.AddStylesheet(textBoxCSS); err != nil {
+		t.Fatal(err)
+	}
+	document.Restyle()
+	text := textChild(t, document)
+
+	if (*text).HasProperty("width") {
+		t.Error("text node inherited width after it was registered as not inherited")
+	}
+	if got := (*text).GetProperty("margin-left"); got != "1" {
+		t.Errorf("text node margin-left = %q, want 1", got)
+	}
+	if got := ComputedStyle(text)["width"]; got != "" {
+		t.Errorf("computed width of text node = %q, want none", got)
+	}
+	if got := ComputedStyle(text)["margin-left"]; got != "1" {
+		t.Errorf("computed margin-left of text node = %q, want 1", got)
+	}
+}
@@ -0,0 +1,174 @@
+package bracelet
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// borderNames maps the lipgloss borders that have a name in CSS to it.
+var borderNames = map[lipgloss.Border]string{
+	lipgloss.NormalBorder():         "normal",
+	lipgloss.RoundedBorder():        "rounded",
+	lipgloss.BlockBorder():          "block",
+	lipgloss.DoubleBorder():         "double",
+	lipgloss.HiddenBorder():         "hidden",
+	lipgloss.InnerHalfBlockBorder(): "inner-half",
+	lipgloss.OuterHalfBlockBorder(): "outer-half",
+	lipgloss.ThickBorder():          "thick",
+}
+
+// StyleDeclarations converts a lipgloss.Style into the declarations that
+// render the same way with the built-in properties. Only what those
+// properties can express is converted: colors must be lipgloss.Color values,
+// borders must use one of the named lipgloss borders, a border background
+// needs a border foreground, and a style that is both underlined and struck
+// through is only underlined. Settings without an equivalent are left out.
+func StyleDeclarations(style lipgloss.Style) []Declaration {
+	var declarations []Declaration
+	add := func(name, value string) {
+		declarations = append(declarations, Declaration{Name: name, Value: value})
+	}
+
+	if color, ok := cssColor(style.GetForeground()); ok {
+		add("color", color)
+	}
+	if color, ok := cssColor(style.GetBackground()); ok {
+		add("background-color", color)
+	}
+	if style.GetBold() {
+		add("font-weight", "bold")
+	}
+	if style.GetItalic() {
+		add("font-style", "italic")
+	}
+	switch {
+	case style.GetUnderline():
+		add("text-decoration", "underline")
+	case style.GetStrikethrough():
+		add("text-decoration", "line-through")
+	}
+
+	if top, right, bottom, left := style.GetMargin(); top != 0 || right != 0 || bottom != 0 || left != 0 {
+		add("margin", boxValue(top, right, bottom, left))
+	}
+	if top, right, bottom, left := style.GetPadding(); top != 0 || right != 0 || bottom != 0 || left != 0 {
+		add("padding", boxValue(top, right, bottom, left))
+	}
+	declarations = append(declarations, borderDeclarations(style)...)
+	if width := style.GetWidth(); width > 0 {
+		add("width", strconv.Itoa(width))
+	}
+	if height := style.GetHeight(); height > 0 {
+		add("height", strconv.Itoa(height))
+	}
+
+	switch style.GetAlignHorizontal() {
+	case lipgloss.Center:
+		add("text-align", "center")
+	case lipgloss.Right:
+		add("text-align", "right")
+	}
+	switch style.GetAlignVertical() {
+	case lipgloss.Center:
+		add("vertical-align", "center")
+	case lipgloss.Bottom:
+		add("vertical-align", "bottom")
+	}
+	return declarations
+}
+
+// borderDeclarations converts the border of style, using the border
+// shorthand when every side looks the same.
+func borderDeclarations(style lipgloss.Style) []Declaration {
+	border, top, right, bottom, left := style.GetBorder()
+	name, named := borderNames[border]
+	if !named {
+		return nil
+	}
+	if !top && !right && !bottom && !left {
+		// A border style without any sides enabled renders on every side.
+		top, right, bottom, left = true, true, true, true
+	}
+
+	values := make([]string, 4)
+	shown := []bool{top, right, bottom, left}
+	foregrounds := []lipgloss.TerminalColor{
+		style.GetBorderTopForeground(), style.GetBorderRightForeground(),
+		style.GetBorderBottomForeground(), style.GetBorderLeftForeground(),
+	}
+	backgrounds := []lipgloss.TerminalColor{
+		style.GetBorderTopBackground(), style.GetBorderRightBackground(),
+		style.GetBorderBottomBackground(), style.GetBorderLeftBackground(),
+	}
+	for i := range values {
+		if !shown[i] {
+			values[i] = "none"
+			continue
+		}
+		value := []string{name}
+		if foreground, ok := cssColor(foregrounds[i]); ok {
+			value = append(value, foreground)
+			if background, ok := cssColor(backgrounds[i]); ok {
+				value = append(value, background)
+			}
+		}
+		values[i] = strings.Join(value, " ")
+	}
+
+	if values[0] == values[1] && values[1] == values[2] && values[2] == values[3] {
+		return []Declaration{{Name: "border", Value: values[0]}}
+	}
+	var declarations []Declaration
+	for i, side := range sides("border") {
+		declarations = append(declarations, Declaration{Name: side, Value: values[i]})
+	}
+	return declarations
+}
+
+// cssColor returns the value of the color property for a lipgloss color, if
+// it has one.
+func cssColor(color lipgloss.TerminalColor) (string, bool) {
+	c, ok := color.(lipgloss.Color)
+	if !ok || c == "" {
+		return "", false
+	}
+	value, err := parseColor(string(c))
+	return value, err == nil
+}
+
+func boxValue(top, right, bottom, left int) string {
+	return strings.Join([]string{
+		strconv.Itoa(top), strconv.Itoa(right), strconv.Itoa(bottom), strconv.Itoa(left),
+	}, " ")
+}
+
+// StyleSheet formats styles as CSS, with one rule per selector that holds
+// the declarations returned by StyleDeclarations. Rules are sorted by
+// selector, and styles without any declarations are left out.
+func StyleSheet(styles map[string]lipgloss.Style) string {
+	selectors := make([]string, 0, len(styles))
+	for selector := range styles {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	var css strings.Builder
+	for _, selector := range selectors {
+		declarations := StyleDeclarations(styles[selector])
+		if len(declarations) == 0 {
+			continue
+		}
+		if css.Len() > 0 {
+			css.WriteString("\n")
+		}
+		css.WriteString(selector + " {\n")
+		for _, declaration := range declarations {
+			css.WriteString("    " + declaration.Name + ": " + declaration.Value + ";\n")
+		}
+		css.WriteString("}\n")
+	}
+	return css.String()
+}
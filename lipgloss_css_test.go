@@ -0,0 +1,71 @@
+package bracelet
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+var convertedStyles = map[string]lipgloss.Style{
+	"#plain": lipgloss.NewStyle(),
+	"#text": lipgloss.NewStyle().Bold(true).Italic(true).Underline(true).
+		Foreground(lipgloss.Color("#ff00cc")).Background(lipgloss.Color("236")),
+	"#box": lipgloss.NewStyle().Margin(1, 2).Padding(0, 1, 2).Width(20).Height(4).
+		Align(lipgloss.Center, lipgloss.Bottom).Border(lipgloss.RoundedBorder()),
+	"#sides": lipgloss.NewStyle().Border(lipgloss.DoubleBorder(), true, false).
+		BorderForeground(lipgloss.Color("#00ff00")).BorderBackground(lipgloss.Color("#000000")),
+	".struck": lipgloss.NewStyle().Strikethrough(true).Align(lipgloss.Right),
+}
+
+func TestStyleDeclarationsRoundTrip(t *testing.T) {
+	for selector, style := range convertedStyles {
+		t.Run(selector, func(t *testing.T) {
+			rules, err := ParseCSS(StyleSheet(map[string]lipgloss.Style{selector: style}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diagnostics := ValidateCSS(rules); len(diagnostics) != 0 {
+				t.Fatalf("converted declarations are invalid: %v", diagnostics)
+			}
+
+			element := NewElement("p")
+			element.ID = selector[1:]
+			element.Classes = []string{selector[1:]}
+			element.SetContent("Some content")
+			var node Node = &element
+			ApplyStylesheet(&node, rules)
+
+			want := style.Render("Some content")
+			if got := node.Serve(); got != want {
+				t.Errorf("converted style renders\n%s\nwant\n%s", got, want)
+			}
+			resolved := ResolveStyle(&node)
+			if resolved.Foreground != style.GetForeground() && style.GetForeground() != (lipgloss.NoColor{}) {
+				t.Errorf("foreground %q, want %v", resolved.Foreground, style.GetForeground())
+			}
+			if resolved.Bold != style.GetBold() || resolved.Italic != style.GetItalic() || resolved.Width != style.GetWidth() {
+				t.Errorf("resolved style %+v differs from the converted style", resolved)
+			}
+		})
+	}
+}
+
+func TestStyleSheet(t *testing.T) {
+	got := StyleSheet(map[string]lipgloss.Style{
+		"p":      lipgloss.NewStyle().Bold(true).MarginLeft(2),
+		"#empty": lipgloss.NewStyle(),
+		"h1, h2": lipgloss.NewStyle().Foreground(lipgloss.Color("#FFF")),
+	})
+	want := `h1, h2 {
+    color: #fff;
+}
+
+p {
+    font-weight: bold;
+    margin: 0 0 0 2;
+}
+`
+	if got != want {
+		t.Errorf("StyleSheet =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -14,26 +14,7 @@ func (s specificity) Less(other specificity) bool {
 type MatchedRule struct {
 	Specificity specificity
 	Rule        Rule
-}
 
-func matchRule(node *Node, rule Rule) *MatchedRule {
-	for _, selector := range rule.Selectors {
-		if selector.Matches(node) {
-			return &MatchedRule{
-				Specificity: selector.Specificity(),
-				Rule:        rule,
-			}
-		}
-	}
-	return nil
-}
-
-func matchingRules(node *Node, stylesheet []Rule) []MatchedRule {
-	var matches []MatchedRule
-	for i := range stylesheet {
-		if match := matchRule(node, stylesheet[i]); match != nil {
-			matches = append(matches, *match)
-		}
-	}
-	return matches
+	// rule is the position of Rule in its stylesheet.
+	rule int
 }
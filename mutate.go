@@ -0,0 +1,200 @@
+package bracelet
+
+import "errors"
+
+var (
+	// ErrNotChild is returned when a reference node is not a child of the given parent.
+	ErrNotChild = errors.New("bracelet: node is not a child of the given parent")
+
+	// ErrCycle is returned when a node would be inserted into its own subtree.
+	ErrCycle = errors.New("bracelet: node cannot be inserted into its own subtree")
+)
+
+// The functions in this file are the supported way of changing the shape of a
+// node tree. Unlike the raw AddChild, SetChild and SetChildren methods of Node,
+// they always keep parent and child pointers consistent: every node in a
+// parent's children has that parent pointer as its parent, and a node is never
+// the child of more than one parent. A node is detached from its previous
+// parent before it is inserted anywhere else.
+
+// AppendChild adds child as the last child of parent.
+func AppendChild(parent, child *Node) error {
+	return insertChild(parent, child, nil, false)
+}
+
+// InsertBefore inserts child into parent immediately before reference. If
+// reference is nil, child is appended.
+func InsertBefore(parent, child, reference *Node) error {
+	return insertChild(parent, child, reference, false)
+}
+
+// InsertAfter inserts child into parent immediately after reference. If
+// reference is nil, child is appended.
+func InsertAfter(parent, child, reference *Node) error {
+	return insertChild(parent, child, reference, true)
+}
+
+// RemoveChild removes child from parent and clears its parent pointer.
+func RemoveChild(parent, child *Node) error {
+	if parent == nil || child == nil {
+		return ErrNotChild
+	}
+	children := (*parent).GetChildren()
+	index := indexOfChild(children, child)
+	if index < 0 {
+		return ErrNotChild
+	}
+	(*parent).SetChildren(removeAt(children, index))
+	(*child).SetParent(nil)
+	treeChanged(parent)
+	return nil
+}
+
+// ReplaceChild replaces oldChild of parent with newChild. The old child is
+// detached from the tree.
+func ReplaceChild(parent, newChild, oldChild *Node) error {
+	if parent == nil || newChild == nil || oldChild == nil {
+		return ErrNotChild
+	}
+	if sameNode(newChild, oldChild) {
+		if indexOfChild((*parent).GetChildren(), oldChild) < 0 {
+			return ErrNotChild
+		}
+		return nil
+	}
+	if indexOfChild((*parent).GetChildren(), oldChild) < 0 {
+		return ErrNotChild
+	}
+	if isInclusiveAncestor(newChild, parent) {
+		return ErrCycle
+	}
+	Detach(newChild)
+
+	children := (*parent).GetChildren()
+	index := indexOfChild(children, oldChild)
+	updated := append([]*Node{}, children...)
+	updated[index] = newChild
+	(*parent).SetChildren(updated)
+	(*newChild).SetParent(parent)
+	(*oldChild).SetParent(nil)
+	adoptInto(parent, newChild)
+	treeChanged(parent)
+	return nil
+}
+
+// Detach removes node from its parent, if it has one.
+func Detach(node *Node) {
+	if node == nil {
+		return
+	}
+	parent := (*node).GetParent()
+	if parent == nil {
+		return
+	}
+	children := (*parent).GetChildren()
+	if index := indexOfChild(children, node); index >= 0 {
+		(*parent).SetChildren(removeAt(children, index))
+	}
+	(*node).SetParent(nil)
+	treeChanged(parent)
+}
+
+// MoveTo moves node so that it becomes the child of newParent at index. If
+// index is out of range, node is appended.
+func MoveTo(node, newParent *Node, index int) error {
+	if node == nil || newParent == nil {
+		return ErrNotChild
+	}
+	if isInclusiveAncestor(node, newParent) {
+		return ErrCycle
+	}
+	Detach(node)
+	var reference *Node
+	if children := (*newParent).GetChildren(); index >= 0 && index < len(children) {
+		reference = children[index]
+	}
+	return insertChild(newParent, node, reference, false)
+}
+
+func insertChild(parent, child, reference *Node, after bool) error {
+	if parent == nil || child == nil {
+		return ErrNotChild
+	}
+	if reference != nil && indexOfChild((*parent).GetChildren(), reference) < 0 {
+		return ErrNotChild
+	}
+	if isInclusiveAncestor(child, parent) {
+		return ErrCycle
+	}
+	if reference != nil && sameNode(child, reference) {
+		return nil
+	}
+	Detach(child)
+
+	children := (*parent).GetChildren()
+	index := len(children)
+	if reference != nil {
+		index = indexOfChild(children, reference)
+		if after {
+			index++
+		}
+	}
+	updated := make([]*Node, 0, len(children)+1)
+	updated = append(updated, children[:index]...)
+	updated = append(updated, child)
+	updated = append(updated, children[index:]...)
+	(*parent).SetChildren(updated)
+	(*child).SetParent(parent)
+	adoptInto(parent, child)
+	treeChanged(parent)
+	return nil
+}
+
+// adoptInto makes child's subtree belong to the document of its new parent.
+func adoptInto(parent, child *Node) {
+	if d := OwnerDocument(parent); d != nil {
+		adoptNode(child, d)
+	}
+}
+
+// treeChanged records that the children of parent have changed.
+func treeChanged(parent *Node) {
+	if d := OwnerDocument(parent); d != nil {
+		d.invalidateIDs()
+		d.invalidateStyle(parent, true)
+	}
+}
+
+// sameNode reports whether two node pointers refer to the same node. Distinct
+// pointers can hold the same node, so the nodes themselves are compared.
+func sameNode(a, b *Node) bool {
+	if a == b {
+		return true
+	}
+	return a != nil && b != nil && *a == *b
+}
+
+// isInclusiveAncestor reports whether ancestor is node or one of its ancestors.
+func isInclusiveAncestor(ancestor, node *Node) bool {
+	for current := node; current != nil; current = (*current).GetParent() {
+		if sameNode(current, ancestor) {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOfChild(children []*Node, child *Node) int {
+	for i, c := range children {
+		if sameNode(c, child) {
+			return i
+		}
+	}
+	return -1
+}
+
+func removeAt(children []*Node, index int) []*Node {
+	updated := make([]*Node, 0, len(children)-1)
+	updated = append(updated, children[:index]...)
+	return append(updated, children[index+1:]...)
+}
@@ -0,0 +1,252 @@
+package bracelet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const mutationHTML = `<ul id="list"><li id="a">A</li><li id="b">B</li><li id="c">C</li></ul><div id="other"></div>`
+
+// mutationTree parses mutationHTML and returns its root together with a new
+// detached li element with the ID d.
+func mutationTree(t *testing.T) (root, d *Node) {
+	t.Helper()
+	parsed, err := ParseHTML(mutationHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	element := NewElement("li")
+	element.ID = "d"
+	var node Node = &element
+	return &parsed, &node
+}
+
+func byID(t *testing.T, root *Node, id string) *Node {
+	t.Helper()
+	node := Find(*root, "#"+id)
+	if node == nil {
+		t.Fatalf("no node with ID %q", id)
+	}
+	return node
+}
+
+// matchedIDs returns the IDs of the nodes matching selector, in document
+// order, separated by spaces.
+func matchedIDs(root *Node, selector string) string {
+	var ids []string
+	for _, node := range FindAll(*root, selector) {
+		ids = append(ids, (*node).GetID())
+	}
+	return strings.Join(ids, " ")
+}
+
+// checkParents fails the test if any child in the tree of root does not have
+// the node holding it as its parent.
+func checkParents(t *testing.T, root *Node) {
+	t.Helper()
+	Walk(root, func(node *Node) WalkAction {
+		for _, child := range (*node).GetChildren() {
+			if parent := (*child).GetParent(); !sameNode(parent, node) {
+				t.Errorf("child %q of %q has the wrong parent", (*child).GetID(), (*node).GetID())
+			}
+		}
+		return WalkContinue
+	})
+}
+
+func checkSelectors(t *testing.T, root *Node, want map[string]string) {
+	t.Helper()
+	for selector, ids := range want {
+		if got := matchedIDs(root, selector); got != ids {
+			t.Errorf("%s matched %q, want %q", selector, got, ids)
+		}
+	}
+}
+
+func TestMutations(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(t *testing.T, root, d *Node) error
+		want   map[string]string
+	}{
+		{
+			name: "InsertBefore",
+			mutate: func(t *testing.T, root, d *Node) error {
+				return InsertBefore(byID(t, root, "list"), d, byID(t, root, "b"))
+			},
+			want: map[string]string{
+				"li":              "a d b c",
+				"li:first-child":  "a",
+				"li:nth-child(2)": "d",
+				"li:last-child":   "c",
+				"#a + li":         "d",
+				"#d ~ li":         "b c",
+			},
+		},
+		{
+			name: "InsertAfter",
+			mutate: func(t *testing.T, root, d *Node) error {
+				return InsertAfter(byID(t, root, "list"), d, byID(t, root, "c"))
+			},
+			want: map[string]string{
+				"li":              "a b c d",
+				"li:first-child":  "a",
+				"li:nth-child(3)": "c",
+				"li:last-child":   "d",
+				"#c + li":         "d",
+				"#a ~ li":         "b c d",
+			},
+		},
+		{
+			name: "RemoveChild",
+			mutate: func(t *testing.T, root, d *Node) error {
+				return RemoveChild(byID(t, root, "list"), byID(t, root, "a"))
+			},
+			want: map[string]string{
+				"li":              "b c",
+				"li:first-child":  "b",
+				"li:nth-child(2)": "c",
+				"li:last-child":   "c",
+				"#b + li":         "c",
+				"li ~ li":         "c",
+			},
+		},
+		{
+			name: "ReplaceChild",
+			mutate: func(t *testing.T, root, d *Node) error {
+				return ReplaceChild(byID(t, root, "list"), d, byID(t, root, "c"))
+			},
+			want: map[string]string{
+				"li":              "a b d",
+				"li:first-child":  "a",
+				"li:nth-child(3)": "d",
+				"li:last-child":   "d",
+				"#b + li":         "d",
+				"#a ~ li":         "b d",
+			},
+		},
+		{
+			name: "Detach",
+			mutate: func(t *testing.T, root, d *Node) error {
+				Detach(byID(t, root, "c"))
+				return nil
+			},
+			want: map[string]string{
+				"li":              "a b",
+				"li:first-child":  "a",
+				"li:nth-child(2)": "b",
+				"li:last-child":   "b",
+				"#a + li":         "b",
+				"#b ~ li":         "",
+			},
+		},
+		{
+			name: "MoveTo within parent",
+			mutate: func(t *testing.T, root, d *Node) error {
+				return MoveTo(byID(t, root, "c"), byID(t, root, "list"), 0)
+			},
+			want: map[string]string{
+				"li":              "c a b",
+				"li:first-child":  "c",
+				"li:nth-child(2)": "a",
+				"li:last-child":   "b",
+				"#c + li":         "a",
+				"#c ~ li":         "a b",
+			},
+		},
+		{
+			name: "MoveTo other parent",
+			mutate: func(t *testing.T, root, d *Node) error {
+				return MoveTo(byID(t, root, "a"), byID(t, root, "other"), 0)
+			},
+			want: map[string]string{
+				"#list > li":      "b c",
+				"#other > li":     "a",
+				"li:first-child":  "b a",
+				"li:nth-child(2)": "c",
+				"li:last-child":   "c a",
+				"#b + li":         "c",
+				"#a ~ li":         "",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, d := mutationTree(t)
+			if err := test.mutate(t, root, d); err != nil {
+				t.Fatal(err)
+			}
+			checkParents(t, root)
+			checkSelectors(t, root, test.want)
+		})
+	}
+}
+
+func TestMutationsClearParentOfRemovedNodes(t *testing.T) {
+	root, d := mutationTree(t)
+	list := byID(t, root, "list")
+
+	a := byID(t, root, "a")
+	if err := RemoveChild(list, a); err != nil {
+		t.Fatal(err)
+	}
+	if (*a).GetParent() != nil {
+		t.Error("RemoveChild left the parent of the removed node set")
+	}
+
+	b := byID(t, root, "b")
+	if err := ReplaceChild(list, d, b); err != nil {
+		t.Fatal(err)
+	}
+	if (*b).GetParent() != nil {
+		t.Error("ReplaceChild left the parent of the replaced node set")
+	}
+	if !sameNode((*d).GetParent(), list) {
+		t.Error("ReplaceChild did not set the parent of the new node")
+	}
+
+	Detach(d)
+	if (*d).GetParent() != nil {
+		t.Error("Detach left the parent set")
+	}
+	checkParents(t, root)
+}
+
+func TestMutationErrors(t *testing.T) {
+	root, d := mutationTree(t)
+	list, a, other := byID(t, root, "list"), byID(t, root, "a"), byID(t, root, "other")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"InsertBefore reference of another parent", InsertBefore(other, d, a), ErrNotChild},
+		{"InsertAfter reference of another parent", InsertAfter(other, d, a), ErrNotChild},
+		{"RemoveChild of another parent", RemoveChild(other, a), ErrNotChild},
+		{"ReplaceChild of another parent", ReplaceChild(other, d, a), ErrNotChild},
+		{"MoveTo nil parent", MoveTo(a, nil, 0), ErrNotChild},
+		{"AppendChild into itself", AppendChild(list, list), ErrCycle},
+		{"AppendChild into descendant", AppendChild(a, list), ErrCycle},
+		{"InsertBefore into descendant", InsertBefore(a, list, nil), ErrCycle},
+		{"ReplaceChild with ancestor", ReplaceChild(list, list, a), ErrCycle},
+		{"MoveTo into descendant", MoveTo(list, a, 0), ErrCycle},
+	}
+	for _, test := range tests {
+		if !errors.Is(test.err, test.want) {
+			t.Errorf("%s returned %v, want %v", test.name, test.err, test.want)
+		}
+	}
+
+	// Failed mutations leave the tree unchanged.
+	checkParents(t, root)
+	checkSelectors(t, root, map[string]string{
+		"#list > li":  "a b c",
+		"#other > li": "",
+	})
+	if (*d).GetParent() != nil {
+		t.Error("a failed mutation attached the new node")
+	}
+}
@@ -107,21 +107,29 @@ type Node interface {
 	SetParent(*Node)
 
 	// SetChildren sets all child nodes of this node, replacing any existing children.
+	// It does not update parent pointers; use AppendChild and the other tree
+	// mutation functions to keep the tree consistent.
 	SetChildren([]*Node)
 
 	// AddChild adds a new child node to this node.
+	// It does not update parent pointers; use AppendChild instead.
 	AddChild(*Node)
 
 	// SetChild sets a child node at a specific index. If the index is out of range, the child is appended.
+	// Use ReplaceChild or MoveTo to keep parent pointers consistent.
 	SetChild(int, *Node)
 }
 
 type NodeFactory func(tag string) Node
 
-var customNodeFactories = make(map[string]NodeFactory)
-
 func createNode(tag string) Node {
-	if factory, ok := customNodeFactories[tag]; ok {
+	return createNodeFrom(DefaultRegistry, tag)
+}
+
+// createNodeFrom creates a node for a tag using the given registry, falling
+// back to a plain Element for unregistered tags.
+func createNodeFrom(registry *Registry, tag string) Node {
+	if factory, ok := registry.nodeFactory(tag); ok {
 		node := factory(tag)
 		if node != nil {
 			return ensureInitialized(node)
@@ -14,15 +14,28 @@ type ImgNode struct {
 }
 
 func (n ImgNode) Create() NodeFactory {
-	canvas := paintbrush.New()
-
 	return func(tag string) Node {
 		return &ImgNode{
 			Element: NewElement(tag),
-			canvas:  canvas,
+			canvas:  paintbrush.New(),
 		}
 	}
+}
 
+// CloneNode returns a copy of the ImgNode with its own canvas, reloading the
+// image and font from the src and font attributes.
+func (n *ImgNode) CloneNode() Node {
+	clone := &ImgNode{
+		Element: CloneElement(n.Element),
+		canvas:  paintbrush.New(),
+	}
+	for _, attr := range []string{"font", "src"} {
+		if value, exists := clone.Attributes[attr]; exists {
+			delete(clone.Attributes, attr)
+			clone.SetAttribute(attr, value)
+		}
+	}
+	return clone
 }
 
 func (n *ImgNode) SetAttribute(attr string, value string) {
@@ -36,7 +49,7 @@ func (n *ImgNode) SetAttribute(attr string, value string) {
 			n.updated = false
 		}
 	}
-	n.Attributes[attr] = value
+	n.Element.SetAttribute(attr, value)
 }
 
 func (n *ImgNode) SetProperty(attr string, value string) {
@@ -49,7 +62,7 @@ func (n *ImgNode) SetProperty(attr string, value string) {
 			n.updated = false
 		}
 	}
-	n.Properties[attr] = value
+	n.Element.SetProperty(attr, value)
 }
 
 func (n *ImgNode) ConvertImage() {
@@ -12,6 +12,9 @@ type PropertyFunction func(string, lipgloss.Style) (string, lipgloss.Style)
 // PropertyFunctions maps CSS property names to their corresponding PropertyFunction.
 // Each PropertyFunction takes a property value as input and returns a function
 // that applies that property to a node's content and style.
+//
+// PropertyFunctions backs DefaultRegistry. Writing to it directly is not safe
+// while other goroutines parse or render; use RegisterPropertyFunction instead.
 var PropertyFunctions = map[string]func(string) PropertyFunction{
 	"color":            PropColor,
 	"background-color": PropBackgroundColor,
@@ -44,15 +47,36 @@ var PropertyFunctions = map[string]func(string) PropertyFunction{
 }
 
 // ApplyProperty looks up the appropriate PropertyFunction and applies it to a node's content and style.
+// Nodes that belong to a Document use the document's property functions.
 // If the property is not recognized, no changes are made to the Node.
 func ApplyProperty(node *Node, property string, value string) {
-	if propFunc, ok := PropertyFunctions[property]; ok {
+	if propFunc, ok := registryFor(*node).propertyFunction(property); ok {
 		content, style := propFunc(value)((*node).GetContent(), (*node).GetStyle())
 		(*node).SetContent(content)
 		(*node).SetStyle(style)
 	}
 }
 
+// applyProperties applies properties to content and a new style using the
+// property functions of registry, in the order used for rendering.
+func (r *Registry) applyProperties(content string, properties map[string]string) (string, lipgloss.Style) {
+	style := lipgloss.NewStyle()
+	for _, key := range r.applicationOrder(properties) {
+		if function, exists := r.propertyFunction(key); exists {
+			content, style = function(properties[key])(content, style)
+		}
+	}
+	return content, style
+}
+
+// storeStyle stores on node the style its properties render with, so
+// that GetStyle returns it after the cascade. Properties that only change
+// content, such as text-transform, have no effect on the style.
+func storeStyle(node *Node) {
+	_, style := registryFor(*node).applyProperties("", (*node).GetProperties())
+	(*node).SetStyle(style)
+}
+
 // PropColor returns a PropertyFunction that sets the foreground color of the text.
 func PropColor(value string) PropertyFunction {
 	return func(content string, style lipgloss.Style) (string, lipgloss.Style) {
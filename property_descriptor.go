@@ -0,0 +1,464 @@
+package bracelet
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PropertyDescriptor describes everything the cascade and renderer need to
+// know about a CSS property. It is registered with RegisterProperty.
+type PropertyDescriptor struct {
+	// Name is the property name, such as "margin-left".
+	Name string
+	// Apply returns the PropertyFunction that renders a value. Properties
+	// without one are still cascaded and inherited but have no effect on
+	// rendering, which is useful for values read by custom nodes.
+	Apply func(string) PropertyFunction
+	// Parse converts a value into its canonical form, reporting an error if
+	// the value is invalid. A nil Parse accepts any value unchanged.
+	Parse func(string) (string, error)
+	// Validate reports an error for parsed values that are syntactically
+	// correct but not allowed, such as negative widths.
+	Validate func(string) error
+	// Inherited, Initial and InheritedBy are the property's metadata, see
+	// PropertyMetadata.
+	Inherited   bool
+	Initial     string
+	InheritedBy []string
+	// Expand splits the value of a shorthand property into the values of
+	// its longhand properties, which are listed in Longhands. Both are nil
+	// for longhand properties.
+	Expand    func(string) (map[string]string, error)
+	Longhands []string
+	// Phase decides when Apply runs while rendering a node, see
+	// PropertyPhase.
+	Phase PropertyPhase
+}
+
+// PropertyPhase orders the application of properties when a node is
+// rendered. Phases run in the order they are declared, and properties in
+// the same phase are applied in order of their names, so that rendering the
+// same properties always gives the same output.
+type PropertyPhase int
+
+const (
+	// PhaseDefault properties are applied after all other phases. It is the
+	// phase of properties registered without one.
+	PhaseDefault PropertyPhase = iota
+	// PhaseContent properties transform the content, such as text-transform.
+	PhaseContent
+	// PhaseText properties style the text, such as color and font-weight.
+	PhaseText
+	// PhaseShorthand properties set several sides of the box at once, such
+	// as margin and border.
+	PhaseShorthand
+	// PhaseLonghand properties set a single side of the box, overriding the
+	// shorthands, such as margin-left.
+	PhaseLonghand
+	// PhaseLayout properties size and align the box, such as width.
+	PhaseLayout
+	// PhaseDerived properties build on values set in earlier phases, such as
+	// indent, which adds to the left margin.
+	PhaseDerived
+)
+
+// applicationRank orders phases for rendering, moving PhaseDefault last.
+func (p PropertyPhase) applicationRank() int {
+	if p == PhaseDefault {
+		return int(PhaseDerived) + 1
+	}
+	return int(p)
+}
+
+// propertySpec holds the parts of a PropertyDescriptor beyond its function
+// and metadata.
+type propertySpec struct {
+	parse     func(string) (string, error)
+	validate  func(string) error
+	expand    func(string) (map[string]string, error)
+	longhands []string
+	phase     PropertyPhase
+}
+
+// Diagnostic reports a declaration that is ignored because its value is
+// invalid for its property.
+type Diagnostic struct {
+	Property string
+	Value    string
+	// Selector is the selector list of the rule the declaration belongs
+	// to. It is empty for declarations in a style attribute.
+	Selector string
+	// Node is the node whose style attribute holds the declaration, or nil
+	// for declarations in a stylesheet.
+	Node *Node
+	Err  error
+}
+
+func (d Diagnostic) Error() string {
+	source := d.Selector
+	if d.Node != nil {
+		source = fmt.Sprintf("style attribute of <%s>", (*d.Node).GetTag())
+	}
+	return fmt.Sprintf("%s: invalid value %q for %s: %v", source, d.Value, d.Property, d.Err)
+}
+
+func (d Diagnostic) Unwrap() error {
+	return d.Err
+}
+
+// RegisterProperty registers a property described by descriptor, replacing
+// the property function, metadata and syntax of any existing property with
+// the same name.
+func (r *Registry) RegisterProperty(descriptor PropertyDescriptor) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen {
+		return ErrRegistryFrozen
+	}
+	if descriptor.Apply != nil {
+		r.properties[descriptor.Name] = descriptor.Apply
+	} else {
+		delete(r.properties, descriptor.Name)
+	}
+	r.metadata[descriptor.Name] = PropertyMetadata{
+		Inherited:   descriptor.Inherited,
+		Initial:     descriptor.Initial,
+		InheritedBy: descriptor.InheritedBy,
+	}
+	r.specs[descriptor.Name] = propertySpec{
+		parse:     descriptor.Parse,
+		validate:  descriptor.Validate,
+		expand:    descriptor.Expand,
+		longhands: descriptor.Longhands,
+		phase:     descriptor.Phase,
+	}
+	r.version++
+	return nil
+}
+
+// Property returns the descriptor of a registered property.
+func (r *Registry) Property(name string) (PropertyDescriptor, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	apply, hasFunction := r.properties[name]
+	metadata, hasMetadata := r.metadata[name]
+	spec, hasSpec := r.specs[name]
+	if !hasFunction && !hasMetadata && !hasSpec {
+		return PropertyDescriptor{}, false
+	}
+	return PropertyDescriptor{
+		Name:        name,
+		Apply:       apply,
+		Parse:       spec.parse,
+		Validate:    spec.validate,
+		Inherited:   metadata.Inherited,
+		Initial:     metadata.Initial,
+		InheritedBy: metadata.InheritedBy,
+		Expand:      spec.expand,
+		Longhands:   spec.longhands,
+		Phase:       spec.phase,
+	}, true
+}
+
+// RegisterProperty registers a property in DefaultRegistry.
+func RegisterProperty(descriptor PropertyDescriptor) error {
+	return DefaultRegistry.RegisterProperty(descriptor)
+}
+
+// parseValue checks a declared value of a property, returning its canonical
+// form. The CSS-wide keywords are valid for every property, as is any value
+// of a property without registered syntax.
+func (r *Registry) parseValue(name, value string) (string, error) {
+	if isCSSWideKeyword(value) || hasVariables(value) {
+		return value, nil
+	}
+	r.mu.RLock()
+	spec, exists := r.specs[name]
+	r.mu.RUnlock()
+	if !exists {
+		return value, nil
+	}
+
+	if spec.expand != nil {
+		longhands, err := spec.expand(value)
+		if err != nil {
+			return "", err
+		}
+		for longhand, longhandValue := range longhands {
+			if _, err := r.parseValue(longhand, longhandValue); err != nil {
+				return "", fmt.Errorf("%s: %w", longhand, err)
+			}
+		}
+		return value, nil
+	}
+
+	if spec.parse != nil {
+		parsed, err := spec.parse(value)
+		if err != nil {
+			return "", err
+		}
+		value = parsed
+	}
+	if spec.validate != nil {
+		if err := spec.validate(value); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+// resolveDeclaration checks a declaration and expands it if it is a
+// shorthand, returning the resulting longhand declarations with canonical
+// values in order of their names. A CSS-wide keyword given to a shorthand
+// applies to each of its longhands.
+func (r *Registry) resolveDeclaration(declaration Declaration) ([]Declaration, error) {
+	value, err := r.parseValue(declaration.Name, declaration.Value)
+	if err != nil {
+		return nil, err
+	}
+	if hasVariables(value) {
+		// Shorthands using var() are expanded once the references are
+		// substituted, when values are computed.
+		return []Declaration{declaration}, nil
+	}
+	longhands, err := r.expandValue(declaration.Name, value)
+	if err != nil {
+		return nil, err
+	}
+	if longhands == nil {
+		declaration.Value = value
+		return []Declaration{declaration}, nil
+	}
+
+	names := make([]string, 0, len(longhands))
+	for name := range longhands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var declarations []Declaration
+	for _, name := range names {
+		expanded, err := r.resolveDeclaration(Declaration{Name: name, Value: longhands[name], Important: declaration.Important})
+		if err != nil {
+			return nil, err
+		}
+		declarations = append(declarations, expanded...)
+	}
+	return declarations, nil
+}
+
+// expandValue returns the longhand values of a shorthand property, or nil if
+// the property is not a shorthand.
+func (r *Registry) expandValue(name, value string) (map[string]string, error) {
+	r.mu.RLock()
+	spec := r.specs[name]
+	r.mu.RUnlock()
+	if spec.expand == nil {
+		return nil, nil
+	}
+	if isCSSWideKeyword(value) {
+		longhands := make(map[string]string, len(spec.longhands))
+		for _, longhand := range spec.longhands {
+			longhands[longhand] = value
+		}
+		return longhands, nil
+	}
+	expand := spec.expand
+	return expand(value)
+}
+
+// Validate checks every declaration of rules against the registered property
+// syntax and reports those that the cascade ignores.
+func (r *Registry) Validate(rules []Rule) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, rule := range rules {
+		for _, declaration := range rule.Declarations {
+			if _, err := r.parseValue(declaration.Name, declaration.Value); err != nil {
+				diagnostics = append(diagnostics, Diagnostic{
+					Property: declaration.Name,
+					Value:    declaration.Value,
+					Selector: selectorList(rule.Selectors).String(),
+					Err:      err,
+				})
+			}
+		}
+	}
+	return diagnostics
+}
+
+// ValidateCSS checks rules against the properties of DefaultRegistry.
+func ValidateCSS(rules []Rule) []Diagnostic {
+	return DefaultRegistry.Validate(rules)
+}
+
+func isCSSWideKeyword(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case keywordInherit, keywordInitial, keywordUnset, keywordRevert:
+		return true
+	}
+	return false
+}
+
+// builtinPropertySpecs holds the syntax and phase of the properties in
+// PropertyFunctions.
+var builtinPropertySpecs = map[string]propertySpec{
+	"color":            {parse: parseColor, phase: PhaseText},
+	"background-color": {parse: parseColor, phase: PhaseText},
+	"font-weight":      {parse: parseKeyword("normal", "bold", "bolder", "lighter"), phase: PhaseText},
+	"text-transform":   {parse: parseKeyword("none", "uppercase", "lowercase", "capitalize"), phase: PhaseContent},
+	"font-style":       {parse: parseKeywords("normal", "italic", "bold"), phase: PhaseText},
+	"text-decoration":  {parse: parseKeyword("none", "underline", "line-through"), phase: PhaseText},
+	"margin":           {expand: expandBox("margin"), longhands: sides("margin"), phase: PhaseShorthand},
+	"margin-top":       {parse: parseInteger, validate: validateNonNegative, phase: PhaseLonghand},
+	"margin-bottom":    {parse: parseInteger, validate: validateNonNegative, phase: PhaseLonghand},
+	"margin-left":      {parse: parseInteger, validate: validateNonNegative, phase: PhaseLonghand},
+	"margin-right":     {parse: parseInteger, validate: validateNonNegative, phase: PhaseLonghand},
+	"padding":          {expand: expandBox("padding"), longhands: sides("padding"), phase: PhaseShorthand},
+	"padding-top":      {parse: parseInteger, validate: validateNonNegative, phase: PhaseLonghand},
+	"padding-bottom":   {parse: parseInteger, validate: validateNonNegative, phase: PhaseLonghand},
+	"padding-left":     {parse: parseInteger, validate: validateNonNegative, phase: PhaseLonghand},
+	"padding-right":    {parse: parseInteger, validate: validateNonNegative, phase: PhaseLonghand},
+	"border":           {expand: expandBorder, longhands: sides("border"), phase: PhaseShorthand},
+	"border-top":       {parse: parseBorder, phase: PhaseLonghand},
+	"border-bottom":    {parse: parseBorder, phase: PhaseLonghand},
+	"border-left":      {parse: parseBorder, phase: PhaseLonghand},
+	"border-right":     {parse: parseBorder, phase: PhaseLonghand},
+	"width":            {parse: parseInteger, validate: validateNonNegative, phase: PhaseLayout},
+	"height":           {parse: parseInteger, validate: validateNonNegative, phase: PhaseLayout},
+	"text-align":       {parse: parseKeyword("left", "center", "right"), phase: PhaseLayout},
+	"vertical-align":   {parse: parseKeyword("top", "center", "bottom"), phase: PhaseLayout},
+	"indent":           {parse: parseInteger, phase: PhaseDerived},
+	"text-indent":      {parse: parseInteger, phase: PhaseDerived},
+	"word-spacing":     {parse: parseInteger, validate: validateNonNegative, phase: PhaseContent},
+	"direction":        {parse: parseKeyword("horizontal", "vertical"), phase: PhaseLayout},
+}
+
+func parseInteger(value string) (string, error) {
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return "", fmt.Errorf("expected an integer")
+	}
+	return strconv.Itoa(number), nil
+}
+
+func validateNonNegative(value string) error {
+	if strings.HasPrefix(value, "-") {
+		return fmt.Errorf("must not be negative")
+	}
+	return nil
+}
+
+// parseColor accepts the colors lipgloss understands: hexadecimal colors
+// such as "#f0c" or "#ff00cc", and ANSI color numbers from 0 to 255.
+func parseColor(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if hex, isHex := strings.CutPrefix(value, "#"); isHex {
+		if _, err := strconv.ParseUint(hex, 16, 32); err == nil && (len(hex) == 3 || len(hex) == 6) {
+			return strings.ToLower(value), nil
+		}
+		return "", fmt.Errorf("expected a hexadecimal color like #ff00cc")
+	}
+	if number, err := strconv.Atoi(value); err == nil && number >= 0 && number <= 255 {
+		return value, nil
+	}
+	return "", fmt.Errorf("expected a hexadecimal color or an ANSI color number")
+}
+
+// parseKeyword returns a parser accepting exactly one of keywords.
+func parseKeyword(keywords ...string) func(string) (string, error) {
+	return func(value string) (string, error) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		for _, keyword := range keywords {
+			if value == keyword {
+				return value, nil
+			}
+		}
+		return "", fmt.Errorf("expected one of %s", strings.Join(keywords, ", "))
+	}
+}
+
+// parseKeywords returns a parser accepting a space-separated list of keywords.
+func parseKeywords(keywords ...string) func(string) (string, error) {
+	single := parseKeyword(keywords...)
+	return func(value string) (string, error) {
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			return "", fmt.Errorf("expected one of %s", strings.Join(keywords, ", "))
+		}
+		for i, field := range fields {
+			parsed, err := single(field)
+			if err != nil {
+				return "", err
+			}
+			fields[i] = parsed
+		}
+		return strings.Join(fields, " "), nil
+	}
+}
+
+// parseBorder accepts the arguments of the border properties: a border
+// style, a visibility keyword and up to two colors.
+func parseBorder(value string) (string, error) {
+	colors := 0
+	fields := strings.Fields(value)
+	for _, field := range fields {
+		switch strings.ToLower(field) {
+		case "true", "1", "on", "yes", "false", "0", "off", "no", "none",
+			"normal", "rounded", "block", "double", "hidden", "inner", "innerhalf", "inner-half", "half", "outer", "outerhalf", "outer-half", "thick":
+			continue
+		}
+		if _, err := parseColor(field); err != nil {
+			return "", fmt.Errorf("unknown border argument %q", field)
+		}
+		if colors++; colors > 2 {
+			return "", fmt.Errorf("too many border colors")
+		}
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// expandBox returns the expansion of a margin or padding shorthand, which
+// takes one to four integers for the top, right, bottom and left sides.
+func expandBox(property string) func(string) (map[string]string, error) {
+	return func(value string) (map[string]string, error) {
+		fields := strings.Fields(value)
+		var top, right, bottom, left string
+		switch len(fields) {
+		case 1:
+			top, right, bottom, left = fields[0], fields[0], fields[0], fields[0]
+		case 2:
+			top, right, bottom, left = fields[0], fields[1], fields[0], fields[1]
+		case 3:
+			top, right, bottom, left = fields[0], fields[1], fields[2], fields[1]
+		case 4:
+			top, right, bottom, left = fields[0], fields[1], fields[2], fields[3]
+		default:
+			return nil, fmt.Errorf("expected one to four values")
+		}
+		return map[string]string{
+			property + "-top":    top,
+			property + "-right":  right,
+			property + "-bottom": bottom,
+			property + "-left":   left,
+		}, nil
+	}
+}
+
+// sides returns the names of the longhands of a box shorthand.
+func sides(property string) []string {
+	return []string{property + "-top", property + "-right", property + "-bottom", property + "-left"}
+}
+
+// expandBorder expands the border shorthand into the same value for each side.
+func expandBorder(value string) (map[string]string, error) {
+	if _, err := parseBorder(value); err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		"border-top":    value,
+		"border-right":  value,
+		"border-bottom": value,
+		"border-left":   value,
+	}, nil
+}
@@ -0,0 +1,112 @@
+package bracelet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateCSS(t *testing.T) {
+	rules, err := ParseCSS(`p { width: abc; color: #FF00CC; margin: 1 -2; } h1, h2 { font-weight: heavy; width: inherit; }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, diagnostic := range ValidateCSS(rules) {
+		got = append(got, diagnostic.Selector+" "+diagnostic.Property+": "+diagnostic.Value)
+	}
+	want := []string{"p width: abc", "p margin: 1 -2", "h1, h2 font-weight: heavy"}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestCascadeIgnoresInvalidValues(t *testing.T) {
+	document := restyledDocument(t, `<p id="text">Text</p>`,
+		`p { width: 5; color: #FF00CC; } p { width: abc; color: red; margin-left: -1; }`)
+	text := document.GetElementByID("text")
+	want := map[string]string{"width": "5", "color": "#ff00cc", "margin-left": ""}
+	for name, value := range want {
+		if got := (*text).GetProperty(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+func TestRegisterProperty(t *testing.T) {
+	errOdd := errors.New("must be even")
+	document := NewDocument()
+	err := document.RegisterProperty(PropertyDescriptor{
+		Name:  "gap",
+		Parse: parseInteger,
+		Validate: func(value string) error {
+			if value[len(value)-1]%2 != 0 {
+				return errOdd
+			}
+			return nil
+		},
+		Inherited: true,
+		Initial:   "0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = document.RegisterProperty(PropertyDescriptor{
+		Name: "spacing",
+		Expand: func(value string) (map[string]string, error) {
+			fields := strings.Fields(value)
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("expected two values")
+			}
+			return map[string]string{"gap": fields[0], "word-spacing": fields[1]}, nil
+		},
+		Longhands: []string{"gap", "word-spacing"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if descriptor, ok := document.Registry().Property("gap"); !ok || !descriptor.Inherited || descriptor.Initial != "0" {
+		t.Errorf("Property(gap) = %+v, %v", descriptor, ok)
+	}
+
+	if err := document.ParseHTML(`<div id="box" style="gap: 3"><p id="text">Text</p></div>`); err != nil {
+		t.Fatal(err)
+	}
+	if err := document.AddStylesheet(`div { gap: 2; } p { spacing: 4 2; } div { spacing: 1; } p { gap: x; }`); err != nil {
+		t.Fatal(err)
+	}
+	document.Restyle()
+
+	// The style attribute's odd gap is invalid, so the stylesheet's applies.
+	if got := (*document.GetElementByID("box")).GetProperty("gap"); got != "2" {
+		t.Errorf("gap of the div = %q, want 2", got)
+	}
+	text := document.GetElementByID("text")
+	if got := (*text).GetProperty("gap"); got != "4" {
+		t.Errorf("gap expanded from spacing = %q, want 4", got)
+	}
+	if got := (*text).GetProperty("word-spacing"); got != "2" {
+		t.Errorf("word-spacing expanded from spacing = %q, want 2", got)
+	}
+
+	var got []string
+	for _, diagnostic := range document.Diagnostics() {
+		got = append(got, diagnostic.Error())
+		if diagnostic.Property == "gap" && diagnostic.Node != nil && !errors.Is(diagnostic, errOdd) {
+			t.Errorf("diagnostic %v does not wrap the validator's error", diagnostic)
+		}
+	}
+	want := []string{
+		`div: invalid value "1" for spacing: expected two values`,
+		`p: invalid value "x" for gap: expected an integer`,
+		`style attribute of <div>: invalid value "3" for gap: must be even`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, ok := DefaultRegistry.Property("gap"); ok {
+		t.Error("registering a property in a document registered it in DefaultRegistry")
+	}
+}
@@ -1,12 +1,225 @@
 package bracelet
 
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
 func init() {
 	RegisterNode("text", &TextNode{})
 	RegisterNode("img", &ImgNode{})
 }
 
+// ErrRegistryFrozen is returned when registering into a frozen Registry.
+var ErrRegistryFrozen = errors.New("bracelet: registry is frozen")
+
+// Registry holds the custom node factories and property functions used to
+// build and render node trees.
+//
+// A Registry is safe for concurrent use: nodes and properties may be
+// registered from one goroutine while others parse and render. Once frozen,
+// a Registry rejects further registrations, which guarantees that all trees
+// built from it see the same set of nodes and properties. Node trees
+// themselves are not synchronized; a single tree must not be mutated or
+// restyled while another goroutine renders it.
+type Registry struct {
+	mu         sync.RWMutex
+	nodes      map[string]NodeFactory
+	properties map[string]func(string) PropertyFunction
+	metadata   map[string]PropertyMetadata
+	specs      map[string]propertySpec
+	frozen     bool
+
+	// version counts property registrations, so that cached renderings and
+	// values checked against older properties can be detected.
+	version uint64
+}
+
+// DefaultRegistry is the registry used by RegisterNode, ParseHTML and nodes
+// that do not belong to a Document. Its property functions are the
+// PropertyFunctions map and its property metadata the PropertyDefaults map.
+var DefaultRegistry = &Registry{
+	nodes:      make(map[string]NodeFactory),
+	properties: PropertyFunctions,
+	metadata:   PropertyDefaults,
+	specs:      builtinPropertySpecs,
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		nodes:      make(map[string]NodeFactory),
+		properties: make(map[string]func(string) PropertyFunction),
+		metadata:   make(map[string]PropertyMetadata),
+		specs:      make(map[string]propertySpec),
+	}
+}
+
+// RegisterNode registers a custom node type for a specific HTML tag.
+// Tags are case-insensitive, matching the lowercased tags produced by ParseHTML.
+func (r *Registry) RegisterNode(tag string, node Node) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen {
+		return ErrRegistryFrozen
+	}
+	r.nodes[strings.ToLower(tag)] = node.Create()
+	return nil
+}
+
+// RegisterPropertyFunction registers the function that applies a CSS property,
+// adding to or replacing any existing function for that property.
+func (r *Registry) RegisterPropertyFunction(name string, function func(string) PropertyFunction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen {
+		return ErrRegistryFrozen
+	}
+	r.properties[name] = function
+	r.version++
+	return nil
+}
+
+// RegisterPropertyMetadata declares whether a property is inherited and what
+// its initial value is, replacing any existing metadata for that property.
+func (r *Registry) RegisterPropertyMetadata(name string, metadata PropertyMetadata) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.frozen {
+		return ErrRegistryFrozen
+	}
+	r.metadata[name] = metadata
+	return nil
+}
+
+// Freeze prevents any further registrations.
+func (r *Registry) Freeze() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.frozen = true
+}
+
+// Frozen reports whether the registry has been frozen.
+func (r *Registry) Frozen() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.frozen
+}
+
+// Snapshot returns an unfrozen copy of the registry. Later registrations in
+// either registry do not affect the other.
+func (r *Registry) Snapshot() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	snapshot := NewRegistry()
+	for tag, factory := range r.nodes {
+		snapshot.nodes[tag] = factory
+	}
+	for name, function := range r.properties {
+		snapshot.properties[name] = function
+	}
+	for name, metadata := range r.metadata {
+		snapshot.metadata[name] = metadata
+	}
+	for name, spec := range r.specs {
+		snapshot.specs[name] = spec
+	}
+	return snapshot
+}
+
+func (r *Registry) nodeFactory(tag string) (NodeFactory, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	factory, ok := r.nodes[strings.ToLower(tag)]
+	return factory, ok
+}
+
+func (r *Registry) propertyVersion() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.version
+}
+
+// applicationOrder returns the names of properties in the order in which
+// they are applied when rendering.
+func (r *Registry) applicationOrder(properties map[string]string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		first, second := r.specs[names[i]].phase.applicationRank(), r.specs[names[j]].phase.applicationRank()
+		if first != second {
+			return first < second
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// longhandNames returns the names of all registered properties that are not
+// shorthands.
+func (r *Registry) longhandNames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	known := make(map[string]bool)
+	for name := range r.properties {
+		known[name] = true
+	}
+	for name := range r.metadata {
+		known[name] = true
+	}
+	for name := range r.specs {
+		known[name] = true
+	}
+	var names []string
+	for name := range known {
+		if r.specs[name].expand == nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *Registry) propertyMetadata(name string) (PropertyMetadata, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	metadata, ok := r.metadata[name]
+	if !ok && isCustomProperty(name) {
+		return PropertyMetadata{Inherited: true}, true
+	}
+	return metadata, ok
+}
+
+func (r *Registry) propertyFunction(name string) (func(string) PropertyFunction, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	function, ok := r.properties[name]
+	return function, ok
+}
+
 // RegisterNode registers a custom node type for a specific HTML tag.
 // This allows users to extend the package with custom element implementations.
-func RegisterNode(tag string, node Node) {
-	customNodeFactories[tag] = node.Create()
+// Tags are case-insensitive, matching the lowercased tags produced by ParseHTML.
+// It returns ErrRegistryFrozen if DefaultRegistry has been frozen.
+func RegisterNode(tag string, node Node) error {
+	return DefaultRegistry.RegisterNode(tag, node)
+}
+
+// RegisterPropertyFunction registers the function that applies a CSS property
+// in DefaultRegistry. Unlike writing to PropertyFunctions directly, it is safe
+// to call while other goroutines render.
+func RegisterPropertyFunction(name string, function func(string) PropertyFunction) error {
+	return DefaultRegistry.RegisterPropertyFunction(name, function)
+}
+
+// RegisterPropertyMetadata declares whether a property is inherited and what
+// its initial value is in DefaultRegistry.
+func RegisterPropertyMetadata(name string, metadata PropertyMetadata) error {
+	return DefaultRegistry.RegisterPropertyMetadata(name, metadata)
 }
@@ -0,0 +1,115 @@
+package bracelet
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+const concurrentHTML = `<div class="card"><h1 id="title">Title</h1><p>Some <em>text</em> here.</p></div>`
+
+const concurrentCSS = `
+.card { border: rounded; padding: 1; }
+h1 { color: #5f87ff; font-weight: bold; }
+p em { font-style: italic; }
+`
+
+func renderConcurrentTree(t *testing.T) string {
+	root, err := ParseHTML(concurrentHTML)
+	if err != nil {
+		t.Error(err)
+		return ""
+	}
+	rules, err := ParseCSS(concurrentCSS)
+	if err != nil {
+		t.Error(err)
+		return ""
+	}
+	ApplyStylesheet(&root, rules)
+	return root.Serve()
+}
+
+// TestRegistryConcurrentUse parses, styles and renders trees from several
+// goroutines while others register nodes and properties, freeze snapshots
+// and render through a document sharing the registry. Run it with -race.
+func TestRegistryConcurrentUse(t *testing.T) {
+	want := renderConcurrentTree(t)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if got := renderConcurrentTree(t); got != want {
+					t.Errorf("concurrent render differs:\n%s\nwant:\n%s", got, want)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			name := fmt.Sprintf("x-concurrent-%d", i)
+			if err := RegisterNode(name, &Element{}); err != nil {
+				t.Error(err)
+			}
+			if err := RegisterPropertyFunction(name, PropColor); err != nil {
+				t.Error(err)
+			}
+			snapshot := DefaultRegistry.Snapshot()
+			snapshot.Freeze()
+			if err := snapshot.RegisterNode(name, &Element{}); !errors.Is(err, ErrRegistryFrozen) {
+				t.Errorf("RegisterNode on a frozen snapshot returned %v, want ErrRegistryFrozen", err)
+			}
+		}
+	}()
+
+	registry := DefaultRegistry.Snapshot()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			if err := registry.RegisterPropertyFunction(fmt.Sprintf("x-document-%d", i), PropColor); err != nil {
+				t.Error(err)
+			}
+		}
+		registry.Freeze()
+	}()
+	for i := 0; i < 20; i++ {
+		document := NewDocumentWithRegistry(registry)
+		if err := document.ParseHTML(concurrentHTML); err != nil {
+			t.Fatal(err)
+		}
+		if err := document.AddStylesheet(concurrentCSS); err != nil {
+			t.Fatal(err)
+		}
+		document.Restyle()
+		if got := document.Render(); got != want {
+			t.Errorf("document render differs:\n%s\nwant:\n%s", got, want)
+		}
+	}
+
+	wg.Wait()
+}
+
+func TestFrozenRegistryRejectsRegistrations(t *testing.T) {
+	registry := NewRegistry()
+	registry.Freeze()
+	if !registry.Frozen() {
+		t.Fatal("Frozen() = false after Freeze")
+	}
+	if err := registry.RegisterNode("widget", &Element{}); !errors.Is(err, ErrRegistryFrozen) {
+		t.Errorf("RegisterNode returned %v, want ErrRegistryFrozen", err)
+	}
+	if err := registry.RegisterPropertyFunction("gap", PropMargin); !errors.Is(err, ErrRegistryFrozen) {
+		t.Errorf("RegisterPropertyFunction returned %v, want ErrRegistryFrozen", err)
+	}
+	if registry.Snapshot().Frozen() {
+		t.Error("Snapshot of a frozen registry is frozen")
+	}
+}
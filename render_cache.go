@@ -0,0 +1,86 @@
+package bracelet
+
+import "sync/atomic"
+
+// renderCache holds the output of the last Serve of an Element. The output is
+// reused until the element's content, attributes, properties or children
+// change, a descendant changes, or a property function is registered in the
+// registry it was rendered with.
+//
+// The output is swapped atomically, so that several goroutines may call Serve
+// on the same tree at once.
+type renderCache struct {
+	// rendered holds a *renderedOutput, which is nil when the cached output
+	// is no longer valid.
+	rendered atomic.Value
+
+	// held suppresses invalidation while a restyle replaces properties that
+	// may end up unchanged.
+	held bool
+}
+
+type renderedOutput struct {
+	output   string
+	registry *Registry
+	version  uint64
+}
+
+func (c *renderCache) lookup(registry *Registry) (string, bool) {
+	rendered, _ := c.rendered.Load().(*renderedOutput)
+	if rendered != nil && rendered.registry == registry && rendered.version == registry.propertyVersion() {
+		renderCacheHits.Add(1)
+		return rendered.output, true
+	}
+	renderCacheMisses.Add(1)
+	return "", false
+}
+
+func (c *renderCache) store(registry *Registry, version uint64, output string) {
+	c.rendered.Store(&renderedOutput{output: output, registry: registry, version: version})
+}
+
+func (c *renderCache) invalidate() {
+	c.rendered.Store((*renderedOutput)(nil))
+}
+
+var renderCacheHits, renderCacheMisses atomic.Uint64
+
+// RenderStats reports how often Serve reused a cached rendering of a node
+// instead of rendering it again.
+type RenderStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// RenderCacheStats returns the render cache statistics accumulated since the
+// program started or since the last call to ResetRenderCacheStats.
+func RenderCacheStats() RenderStats {
+	return RenderStats{Hits: renderCacheHits.Load(), Misses: renderCacheMisses.Load()}
+}
+
+// ResetRenderCacheStats sets the render cache statistics back to zero.
+func ResetRenderCacheStats() {
+	renderCacheHits.Store(0)
+	renderCacheMisses.Store(0)
+}
+
+// InvalidateRender discards the cached rendering of node and its ancestors.
+// Changes made through Node methods and the tree mutation functions do this
+// automatically. Custom nodes whose Serve output depends on their own fields
+// should call it when those fields change.
+func InvalidateRender(node *Node) {
+	for current := node; current != nil; current = (*current).GetParent() {
+		if embedded, ok := (*current).(embeddedElement); ok {
+			embedded.element().cache.invalidate()
+		}
+	}
+}
+
+// renderChanged discards the cached rendering of the element and its ancestors.
+func (n *Element) renderChanged() {
+	if n.cache.held {
+		return
+	}
+	n.cache.invalidate()
+	InvalidateRender(n.Parent)
+}
@@ -0,0 +1,59 @@
+package bracelet
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestServeSharedTreeConcurrently renders one styled tree from several
+// goroutines at once, which must be free of data races. Run it with -race.
+func TestServeSharedTreeConcurrently(t *testing.T) {
+	root, err := ParseHTML(concurrentHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseCSS(concurrentCSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStylesheet(&root, rules)
+	want := renderConcurrentTree(t)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if got := root.Serve(); got != want {
+					t.Errorf("concurrent Serve differs:\n%s\nwant:\n%s", got, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestServeReusesCachedOutput(t *testing.T) {
+	root, err := ParseHTML(concurrentHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := root.Serve()
+
+	ResetRenderCacheStats()
+	if second := root.Serve(); second != first {
+		t.Fatalf("second Serve differs:\n%s\nwant:\n%s", second, first)
+	}
+	if stats := RenderCacheStats(); stats.Hits != 1 || stats.Misses != 0 {
+		t.Errorf("stats after cached Serve = %+v, want one hit and no misses", stats)
+	}
+
+	// The heading's text is held by a text node child.
+	text := (*Find(root, "#title")).GetChildren()[0]
+	(*text).SetContent("Changed")
+	if third := root.Serve(); third == first {
+		t.Error("Serve returned the cached output after a descendant changed")
+	}
+}
@@ -0,0 +1,201 @@
+package bracelet
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ResolvedStyle is the computed style of a node in typed form, for code that
+// needs to inspect how a node will be rendered without parsing property
+// values itself.
+type ResolvedStyle struct {
+	Foreground    lipgloss.Color
+	Background    lipgloss.Color
+	Bold          bool
+	Italic        bool
+	Underline     bool
+	Strikethrough bool
+	// TextTransform is one of none, uppercase, lowercase and capitalize.
+	TextTransform string
+
+	Margin  BoxSides
+	Padding BoxSides
+	Border  ResolvedBorder
+	Width   int
+	Height  int
+
+	TextAlign     lipgloss.Position
+	VerticalAlign lipgloss.Position
+	// Indent is the sum of indent and text-indent, which are rendered as
+	// additional left margin.
+	Indent      int
+	WordSpacing int
+	// Direction is horizontal or vertical, or empty for the default layout.
+	Direction string
+}
+
+// BoxSides holds a length for each side of a box.
+type BoxSides struct {
+	Top, Right, Bottom, Left int
+}
+
+// ResolvedBorder describes the border of a node. All sides share a single
+// border style, since lipgloss only supports one per style.
+type ResolvedBorder struct {
+	Style                    lipgloss.Border
+	Top, Right, Bottom, Left BorderSide
+}
+
+// BorderSide describes one side of a border.
+type BorderSide struct {
+	Visible    bool
+	Foreground lipgloss.Color
+	Background lipgloss.Color
+}
+
+// ResolveStyle returns the computed style of node in typed form. It is built
+// from ComputedStyle, so it reflects the node's properties rather than the
+// style stored on the node.
+func ResolveStyle(node *Node) ResolvedStyle {
+	return resolveStyle(ComputedStyle(node))
+}
+
+func resolveStyle(values ComputedValues) ResolvedStyle {
+	style := ResolvedStyle{
+		Foreground:    lipgloss.Color(values["color"]),
+		Background:    lipgloss.Color(values["background-color"]),
+		Bold:          values["font-weight"] == "bold",
+		Underline:     values["text-decoration"] == "underline",
+		Strikethrough: values["text-decoration"] == "line-through",
+		TextTransform: values["text-transform"],
+		Margin:        boxSides(values, "margin"),
+		Padding:       boxSides(values, "padding"),
+		Width:         values.Int("width"),
+		Height:        values.Int("height"),
+		TextAlign:     horizontalPosition(values["text-align"]),
+		VerticalAlign: verticalPosition(values["vertical-align"]),
+		Indent:        values.Int("indent") + values.Int("text-indent"),
+		WordSpacing:   values.Int("word-spacing"),
+		Direction:     values["direction"],
+	}
+	for _, keyword := range strings.Fields(values["font-style"]) {
+		switch keyword {
+		case "italic":
+			style.Italic = true
+		case "bold":
+			style.Bold = true
+		}
+	}
+
+	sides := []*BorderSide{&style.Border.Top, &style.Border.Right, &style.Border.Bottom, &style.Border.Left}
+	for i, name := range []string{"border-top", "border-right", "border-bottom", "border-left"} {
+		value, exists := values[name]
+		if !exists {
+			continue
+		}
+		show, borderStyle, foreground, background := parseBorderArgs(strings.Fields(value))
+		if show {
+			*sides[i] = BorderSide{Visible: true, Foreground: foreground, Background: background}
+			style.Border.Style = borderStyle
+		}
+	}
+	return style
+}
+
+func boxSides(values ComputedValues, property string) BoxSides {
+	return BoxSides{
+		Top:    values.Int(property + "-top"),
+		Right:  values.Int(property + "-right"),
+		Bottom: values.Int(property + "-bottom"),
+		Left:   values.Int(property + "-left"),
+	}
+}
+
+func horizontalPosition(value string) lipgloss.Position {
+	switch strings.ToLower(value) {
+	case "center":
+		return lipgloss.Center
+	case "right":
+		return lipgloss.Right
+	default:
+		return lipgloss.Left
+	}
+}
+
+func verticalPosition(value string) lipgloss.Position {
+	switch strings.ToLower(value) {
+	case "center":
+		return lipgloss.Center
+	case "bottom":
+		return lipgloss.Bottom
+	default:
+		return lipgloss.Top
+	}
+}
+
+// Lipgloss returns the lipgloss.Style equivalent to s. Text transforms and
+// word spacing change the content rather than the style, so they are not
+// part of it.
+func (s ResolvedStyle) Lipgloss() lipgloss.Style {
+	style := lipgloss.NewStyle()
+	if s.Bold {
+		style = style.Bold(true)
+	}
+	if s.Italic {
+		style = style.Italic(true)
+	}
+	if s.Underline {
+		style = style.Underline(true)
+	}
+	if s.Strikethrough {
+		style = style.Strikethrough(true)
+	}
+	if margin := s.Margin; margin != (BoxSides{}) || s.Indent != 0 {
+		style = style.Margin(margin.Top, margin.Right, margin.Bottom, margin.Left+s.Indent)
+	}
+	if padding := s.Padding; padding != (BoxSides{}) {
+		style = style.Padding(padding.Top, padding.Right, padding.Bottom, padding.Left)
+	}
+	if s.Width != 0 {
+		style = style.Width(s.Width)
+	}
+	if s.Height != 0 {
+		style = style.Height(s.Height)
+	}
+	if s.TextAlign != lipgloss.Left {
+		style = style.AlignHorizontal(s.TextAlign)
+	}
+	if s.VerticalAlign != lipgloss.Top {
+		style = style.AlignVertical(s.VerticalAlign)
+	}
+	if s.Foreground != "" {
+		style = style.Foreground(s.Foreground)
+	}
+	if s.Background != "" {
+		style = style.Background(s.Background)
+	}
+
+	border := s.Border
+	if !border.Top.Visible && !border.Right.Visible && !border.Bottom.Visible && !border.Left.Visible {
+		return style
+	}
+	style = style.BorderStyle(border.Style).
+		BorderTop(border.Top.Visible).
+		BorderRight(border.Right.Visible).
+		BorderBottom(border.Bottom.Visible).
+		BorderLeft(border.Left.Visible)
+	if side := border.Top; side.Foreground != "" || side.Background != "" {
+		style = style.BorderTopForeground(side.Foreground).BorderTopBackground(side.Background)
+	}
+	if side := border.Right; side.Foreground != "" || side.Background != "" {
+		style = style.BorderRightForeground(side.Foreground).BorderRightBackground(side.Background)
+	}
+	if side := border.Bottom; side.Foreground != "" || side.Background != "" {
+		style = style.BorderBottomForeground(side.Foreground).BorderBottomBackground(side.Background)
+	}
+	if side := border.Left; side.Foreground != "" || side.Background != "" {
+		style = style.BorderLeftForeground(side.Foreground).BorderLeftBackground(side.Background)
+	}
+	return style
+}
@@ -0,0 +1,80 @@
+package bracelet
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const resolvedCSS = `
+div { color: #ff0000; background-color: #000000; text-align: center; }
+p {
+	font-style: italic bold;
+	text-decoration: underline;
+	margin: 1 2;
+	padding-left: 3;
+	indent: 2;
+	width: 20;
+	border: rounded #00ff00;
+	border-bottom: false;
+	direction: vertical;
+}
+`
+
+func TestResolveStyle(t *testing.T) {
+	document := restyledDocument(t, `<div id="box"><p id="text">Text</p></div>`, resolvedCSS)
+	style := ResolveStyle(document.GetElementByID("text"))
+
+	want := ResolvedStyle{
+		Foreground:    "#ff0000",
+		Bold:          true,
+		Italic:        true,
+		Underline:     true,
+		TextTransform: "none",
+		Margin:        BoxSides{Top: 1, Right: 2, Bottom: 1, Left: 2},
+		Padding:       BoxSides{Left: 3},
+		Border: ResolvedBorder{
+			Style: lipgloss.RoundedBorder(),
+			Top:   BorderSide{Visible: true, Foreground: "#00ff00"},
+			Right: BorderSide{Visible: true, Foreground: "#00ff00"},
+			Left:  BorderSide{Visible: true, Foreground: "#00ff00"},
+		},
+		Width:         20,
+		TextAlign:     lipgloss.Center,
+		VerticalAlign: lipgloss.Top,
+		Indent:        2,
+		WordSpacing:   1,
+		Direction:     "vertical",
+	}
+	if style != want {
+		t.Errorf("ResolveStyle =\n%+v\nwant\n%+v", style, want)
+	}
+}
+
+func TestStyleIsStoredAfterCascade(t *testing.T) {
+	document := restyledDocument(t, `<div id="box"><p id="text">Text</p></div>`, resolvedCSS)
+	for _, id := range []string{"box", "text"} {
+		node := document.GetElementByID(id)
+		want := ResolveStyle(node).Lipgloss()
+		got := (*node).GetStyle()
+		if got.Render("content") != want.Render("content") {
+			t.Errorf("GetStyle of #%s renders\n%s\nwant\n%s", id, got.Render("content"), want.Render("content"))
+		}
+		if got.GetMarginLeft() != want.GetMarginLeft() || got.GetWidth() != want.GetWidth() ||
+			got.GetBorderBottom() != want.GetBorderBottom() || got.GetAlignHorizontal() != want.GetAlignHorizontal() {
+			t.Errorf("GetStyle of #%s differs from the resolved style", id)
+		}
+	}
+
+	text := document.GetElementByID("text")
+	if got := (*text).GetStyle(); got.GetMarginLeft() != 4 || !got.GetItalic() || got.GetBorderBottom() {
+		t.Errorf("GetStyle of the p has margin-left %d, italic %v and bottom border %v, want 4, true and false",
+			got.GetMarginLeft(), got.GetItalic(), got.GetBorderBottom())
+	}
+
+	(*text).SetAttribute("style", "margin-left: 0; indent: 0")
+	document.Restyle()
+	if got := (*text).GetStyle().GetMarginLeft(); got != 0 {
+		t.Errorf("GetStyle margin-left after restyle = %d, want 0", got)
+	}
+}
@@ -0,0 +1,409 @@
+package bracelet
+
+import (
+	"sort"
+	"strings"
+)
+
+// RuleIndex groups the selectors of a stylesheet by the ID, class or tag of
+// their rightmost compound selector, the part that must match the node being
+// styled. Matching a node then only tests the selectors in the buckets for
+// its own ID, classes and tag, instead of every rule in the stylesheet.
+//
+// Each selector also records the IDs, classes and tags its descendant and
+// child combinators require of the node's ancestors. When a whole subtree is
+// styled, a filter of the ancestors' IDs, classes and tags is kept, and
+// selectors whose requirements are missing from it are rejected without
+// walking up the tree.
+type RuleIndex struct {
+	rules     []Rule
+	origins   []Origin
+	layers    [][]int
+	entries   []indexedSelector
+	ids       map[string][]int
+	classes   map[string][]int
+	tags      map[string][]int
+	universal []int
+	checked   map[checkedValueKey][]Declaration
+
+	// relational is set when a selector uses :has(), so that a node's style
+	// can depend on its descendants.
+	relational bool
+	// siblingFilters is set when a selector uses :nth-child(An+B of S) or a
+	// variant, so that a node's style can depend on its siblings' attributes.
+	siblingFilters bool
+	// backwardSiblings is set when a selector uses :last-child,
+	// :only-child, :nth-last-child or an -of-type variant, so that a node's
+	// style can depend on its following siblings.
+	backwardSiblings bool
+	// emptiness is set when a selector uses :empty, so that a node's style
+	// can depend on its content and its children's.
+	emptiness bool
+}
+
+// indexedSelector is a single selector of a rule in a RuleIndex.
+type indexedSelector struct {
+	rule      int
+	selector  Selector
+	ancestors []uint32
+}
+
+// NewRuleIndex builds an index of the selectors of rules, which are treated
+// as a single author stylesheet.
+func NewRuleIndex(rules []Rule) *RuleIndex {
+	return newStylesheetIndex([]Stylesheet{{Rules: rules}})
+}
+
+// newStylesheetIndex builds an index of the rules of stylesheets, recording
+// the origin and cascade layer of each rule.
+func newStylesheetIndex(stylesheets []Stylesheet) *RuleIndex {
+	index := collectRules(stylesheets)
+	index.ids = make(map[string][]int)
+	index.classes = make(map[string][]int)
+	index.tags = make(map[string][]int)
+	for i, rule := range index.rules {
+		for _, selector := range rule.Selectors {
+			entry := len(index.entries)
+			index.entries = append(index.entries, indexedSelector{
+				rule:      i,
+				selector:  selector,
+				ancestors: requiredAncestorKeys(selector),
+			})
+
+			compound := subjectCompound(selector)
+			switch {
+			case compound == nil:
+				index.universal = append(index.universal, entry)
+			case compound.ID != "":
+				index.ids[compound.ID] = append(index.ids[compound.ID], entry)
+			case len(compound.Classes) > 0:
+				index.classes[compound.Classes[0]] = append(index.classes[compound.Classes[0]], entry)
+			case compound.Tag != "" && compound.Tag != "*":
+				tag := strings.ToLower(compound.Tag)
+				index.tags[tag] = append(index.tags[tag], entry)
+			default:
+				index.universal = append(index.universal, entry)
+			}
+
+			walkSelector(selector, func(part Selector) {
+				switch part := part.(type) {
+				case *hasSelector:
+					index.relational = true
+				case *nthChildSelector:
+					index.siblingFilters = index.siblingFilters || part.Of != nil
+					index.backwardSiblings = index.backwardSiblings || part.Last
+				case *lastChildSelector, *lastOfTypeSelector, *onlyChildSelector, *onlyOfTypeSelector:
+					index.backwardSiblings = true
+				case *emptySelector:
+					index.emptiness = true
+				}
+			})
+		}
+	}
+	return index
+}
+
+// collectRules returns a RuleIndex holding the rules of stylesheets with
+// their origins and cascade layers, but without any selectors indexed. It can
+// only match nodes with scan.
+func collectRules(stylesheets []Stylesheet) *RuleIndex {
+	index := &RuleIndex{}
+	ranks := layerRanks(stylesheets)
+	for _, stylesheet := range stylesheets {
+		for _, rule := range stylesheet.Rules {
+			index.rules = append(index.rules, rule)
+			index.origins = append(index.origins, stylesheet.Origin)
+			index.layers = append(index.layers, layerPath(rule.Layer, ranks))
+		}
+	}
+	return index
+}
+
+// scan returns the rules that match node like Match, but tests every
+// selector of every rule in turn. For a single node this is cheaper than
+// building the index.
+func (x *RuleIndex) scan(node *Node) []MatchedRule {
+	var matches []MatchedRule
+	for i, rule := range x.rules {
+		matched := false
+		var best specificity
+		for _, selector := range rule.Selectors {
+			if !selector.Matches(node) {
+				continue
+			}
+			if spec := selector.Specificity(); !matched || best.Less(spec) {
+				best = spec
+			}
+			matched = true
+		}
+		if matched {
+			matches = append(matches, MatchedRule{Specificity: best, Rule: rule, rule: i})
+		}
+	}
+	return matches
+}
+
+// Match returns the rules that match node in stylesheet order, each with the
+// specificity of its most specific matching selector.
+func (x *RuleIndex) Match(node *Node) []MatchedRule {
+	return x.match(node, nil)
+}
+
+func (x *RuleIndex) match(node *Node, filter *ancestorFilter) []MatchedRule {
+	candidates := append([]int{}, x.universal...)
+	if id := (*node).GetID(); id != "" {
+		candidates = append(candidates, x.ids[id]...)
+	}
+	for _, class := range (*node).GetClasses() {
+		candidates = append(candidates, x.classes[class]...)
+	}
+	candidates = append(candidates, x.tags[strings.ToLower((*node).GetTag())]...)
+	sort.Ints(candidates)
+
+	var matches []MatchedRule
+	for i, candidate := range candidates {
+		if i > 0 && candidates[i-1] == candidate {
+			continue
+		}
+		entry := x.entries[candidate]
+		if filter != nil && !filter.admits(entry.ancestors) {
+			continue
+		}
+		if !entry.selector.Matches(node) {
+			continue
+		}
+		spec := entry.selector.Specificity()
+		if last := len(matches) - 1; last >= 0 && matches[last].rule == entry.rule {
+			if matches[last].Specificity.Less(spec) {
+				matches[last].Specificity = spec
+			}
+			continue
+		}
+		matches = append(matches, MatchedRule{Specificity: spec, Rule: x.rules[entry.rule], rule: entry.rule})
+	}
+	return matches
+}
+
+// DetermineProperties is like the DetermineProperties function, but only
+// tests node against the rules in the index buckets for its ID, classes and
+// tag. Building the index once and reusing it for many nodes is faster than
+// calling DetermineProperties for each of them.
+func (x *RuleIndex) DetermineProperties(node *Node) map[string]string {
+	return computeProperties(node, x.properties(node, x.Match(node)))
+}
+
+// cascade computes the properties of node and its descendants, parents
+// before children, and passes them to apply.
+func (x *RuleIndex) cascade(node *Node, apply func(*Node, map[string]string)) {
+	filter := &ancestorFilter{}
+	for parent := (*node).GetParent(); parent != nil; parent = (*parent).GetParent() {
+		filter.push(parent)
+	}
+	x.cascadeWith(node, filter, apply)
+}
+
+func (x *RuleIndex) cascadeWith(node *Node, filter *ancestorFilter, apply func(*Node, map[string]string)) {
+	apply(node, computeProperties(node, x.properties(node, x.match(node, filter))))
+	children := (*node).GetChildren()
+	if len(children) == 0 {
+		return
+	}
+	filter.push(node)
+	for _, child := range children {
+		x.cascadeWith(child, filter, apply)
+	}
+	filter.pop(node)
+}
+
+// walkSelector calls visit for selector and every selector it is built
+// from, including the compounds joined by combinators and the arguments of
+// :is(), :where(), :not(), :has() and :nth-child(An+B of S).
+func walkSelector(selector Selector, visit func(Selector)) {
+	visit(selector)
+	switch s := selector.(type) {
+	case selectorList:
+		for _, item := range s {
+			walkSelector(item, visit)
+		}
+	case *descendantSelector:
+		walkSelector(s.Ancestor, visit)
+		walkSelector(s.Descendant, visit)
+	case *childSelector:
+		walkSelector(s.Parent, visit)
+		walkSelector(s.Child, visit)
+	case *adjacentSiblingSelector:
+		walkSelector(s.First, visit)
+		walkSelector(s.Second, visit)
+	case *generalSiblingSelector:
+		walkSelector(s.First, visit)
+		walkSelector(s.Second, visit)
+	case *isSelector:
+		walkSelector(s.Base, visit)
+		walkSelector(s.Arguments, visit)
+	case *whereSelector:
+		walkSelector(s.Base, visit)
+		walkSelector(s.Arguments, visit)
+	case *notSelector:
+		walkSelector(s.Base, visit)
+		walkSelector(s.Negation, visit)
+	case *hasSelector:
+		walkSelector(s.Base, visit)
+		for _, argument := range s.Arguments {
+			for _, step := range argument.Steps {
+				walkSelector(step.Compound, visit)
+			}
+		}
+	case *nthChildSelector:
+		walkSelector(s.Selector, visit)
+		if s.Of != nil {
+			walkSelector(s.Of, visit)
+		}
+	case *firstChildSelector:
+		walkSelector(s.Selector, visit)
+	case *firstOfTypeSelector:
+		walkSelector(s.Selector, visit)
+	case *lastChildSelector:
+		walkSelector(s.Selector, visit)
+	case *lastOfTypeSelector:
+		walkSelector(s.Selector, visit)
+	case *onlyChildSelector:
+		walkSelector(s.Selector, visit)
+	case *onlyOfTypeSelector:
+		walkSelector(s.Selector, visit)
+	case *emptySelector:
+		walkSelector(s.Selector, visit)
+	case *rootSelector:
+		walkSelector(s.Selector, visit)
+	}
+}
+
+// subjectCompound returns the compound selector that must match the node a
+// selector applies to, or nil if there is none to index by.
+func subjectCompound(selector Selector) *simpleSelector {
+	switch s := selector.(type) {
+	case *simpleSelector:
+		return s
+	case *descendantSelector:
+		return subjectCompound(s.Descendant)
+	case *childSelector:
+		return subjectCompound(s.Child)
+	case *adjacentSiblingSelector:
+		return subjectCompound(s.Second)
+	case *generalSiblingSelector:
+		return subjectCompound(s.Second)
+	case *isSelector:
+		return subjectCompound(s.Base)
+	case *whereSelector:
+		return subjectCompound(s.Base)
+	case *notSelector:
+		return subjectCompound(s.Base)
+	case *hasSelector:
+		return subjectCompound(s.Base)
+	case *firstChildSelector:
+		return subjectCompound(s.Selector)
+	case *firstOfTypeSelector:
+		return subjectCompound(s.Selector)
+	case *lastChildSelector:
+		return subjectCompound(s.Selector)
+	case *lastOfTypeSelector:
+		return subjectCompound(s.Selector)
+	case *onlyChildSelector:
+		return subjectCompound(s.Selector)
+	case *onlyOfTypeSelector:
+		return subjectCompound(s.Selector)
+	case *nthChildSelector:
+		return subjectCompound(s.Selector)
+	case *emptySelector:
+		return subjectCompound(s.Selector)
+	case *rootSelector:
+		return subjectCompound(s.Selector)
+	}
+	return nil
+}
+
+// requiredAncestorKeys returns the hashed IDs, classes and tags that some
+// ancestor of a matching node must have.
+func requiredAncestorKeys(selector Selector) []uint32 {
+	switch s := selector.(type) {
+	case *descendantSelector:
+		return append(append(requiredAncestorKeys(s.Ancestor), compoundKeys(s.Ancestor)...), requiredAncestorKeys(s.Descendant)...)
+	case *childSelector:
+		return append(append(requiredAncestorKeys(s.Parent), compoundKeys(s.Parent)...), requiredAncestorKeys(s.Child)...)
+	case *adjacentSiblingSelector:
+		return append(requiredAncestorKeys(s.First), requiredAncestorKeys(s.Second)...)
+	case *generalSiblingSelector:
+		return append(requiredAncestorKeys(s.First), requiredAncestorKeys(s.Second)...)
+	}
+	return nil
+}
+
+// compoundKeys returns the hashed ID, classes and tag required by the
+// subject compound of a selector.
+func compoundKeys(selector Selector) []uint32 {
+	compound := subjectCompound(selector)
+	if compound == nil {
+		return nil
+	}
+	var keys []uint32
+	if compound.ID != "" {
+		keys = append(keys, hashAncestorKey('#', compound.ID))
+	}
+	for _, class := range compound.Classes {
+		keys = append(keys, hashAncestorKey('.', class))
+	}
+	if compound.Tag != "" && compound.Tag != "*" {
+		keys = append(keys, hashAncestorKey('<', strings.ToLower(compound.Tag)))
+	}
+	return keys
+}
+
+const ancestorFilterSize = 1 << 12
+
+// ancestorFilter is a counting Bloom filter of the IDs, classes and tags of
+// the ancestors of the node being styled. It can report false positives but
+// never false negatives, so it is only used to reject selectors.
+type ancestorFilter struct {
+	counts [ancestorFilterSize]uint16
+}
+
+func (f *ancestorFilter) push(node *Node) {
+	f.each(node, func(slot *uint16) { *slot++ })
+}
+
+func (f *ancestorFilter) pop(node *Node) {
+	f.each(node, func(slot *uint16) { *slot-- })
+}
+
+func (f *ancestorFilter) each(node *Node, update func(*uint16)) {
+	add := func(key uint32) {
+		update(&f.counts[key%ancestorFilterSize])
+		update(&f.counts[(key>>16)%ancestorFilterSize])
+	}
+	if id := (*node).GetID(); id != "" {
+		add(hashAncestorKey('#', id))
+	}
+	for _, class := range (*node).GetClasses() {
+		add(hashAncestorKey('.', class))
+	}
+	add(hashAncestorKey('<', strings.ToLower((*node).GetTag())))
+}
+
+// admits reports whether all keys may be present in the filter.
+func (f *ancestorFilter) admits(keys []uint32) bool {
+	for _, key := range keys {
+		if f.counts[key%ancestorFilterSize] == 0 || f.counts[(key>>16)%ancestorFilterSize] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashAncestorKey hashes a kind prefix and a name with 32-bit FNV-1a.
+func hashAncestorKey(kind byte, name string) uint32 {
+	hash := uint32(2166136261)
+	hash = (hash ^ uint32(kind)) * 16777619
+	for i := 0; i < len(name); i++ {
+		hash = (hash ^ uint32(name[i])) * 16777619
+	}
+	return hash
+}
@@ -0,0 +1,147 @@
+package bracelet
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestRuleIndexSelectorFlags(t *testing.T) {
+	tests := []struct {
+		css                        string
+		relational, siblingFilters bool
+	}{
+		{`p { color: 1; }`, false, false},
+		{`[title="x of y"] { color: 1; }`, false, false},
+		{`[title=":has(b)"] { color: 1; }`, false, false},
+		{`div:has(> p) { color: 1; }`, true, false},
+		{`ul > :is(li:has(a), p) { color: 1; }`, true, false},
+		{`a:not(:has(b)) + p { color: 1; }`, true, false},
+		{`li:nth-child(2 of .item) { color: 1; }`, false, true},
+		{`li:nth-last-child(odd of :not(.hidden)) { color: 1; }`, false, true},
+		{`li:nth-child(2) { color: 1; }`, false, false},
+		{`ul :where(li:nth-child(1 of .a)) ~ p:has(em) { color: 1; }`, true, true},
+	}
+	for _, test := range tests {
+		rules, err := ParseCSS(test.css)
+		if err != nil || len(rules) != 1 {
+			t.Fatalf("%s: parsed %d rules, error %v", test.css, len(rules), err)
+		}
+		index := NewRuleIndex(rules)
+		if index.relational != test.relational {
+			t.Errorf("%s: relational = %v, want %v", test.css, index.relational, test.relational)
+		}
+		if index.siblingFilters != test.siblingFilters {
+			t.Errorf("%s: siblingFilters = %v, want %v", test.css, index.siblingFilters, test.siblingFilters)
+		}
+	}
+}
+
+func TestRuleIndexRestyleFlags(t *testing.T) {
+	tests := []struct {
+		css                         string
+		backwardSiblings, emptiness bool
+	}{
+		{`li:first-child, li:nth-child(2) { color: 1; }`, false, false},
+		{`li:last-child { color: 1; }`, true, false},
+		{`:is(li:only-of-type) { color: 1; }`, true, false},
+		{`li:nth-last-of-type(2) { color: 1; }`, true, false},
+		{`div:not(:empty) > p { color: 1; }`, false, true},
+	}
+	for _, test := range tests {
+		rules, err := ParseCSS(test.css)
+		if err != nil || len(rules) != 1 {
+			t.Fatalf("%s: parsed %d rules, error %v", test.css, len(rules), err)
+		}
+		index := NewRuleIndex(rules)
+		if index.backwardSiblings != test.backwardSiblings {
+			t.Errorf("%s: backwardSiblings = %v, want %v", test.css, index.backwardSiblings, test.backwardSiblings)
+		}
+		if index.emptiness != test.emptiness {
+			t.Errorf("%s: emptiness = %v, want %v", test.css, index.emptiness, test.emptiness)
+		}
+	}
+}
+
+const matchingHTML = `<div id="app" class="card"><ul><li class="item">A</li><li class="item active">B</li><li>C</li></ul><p>Some <em>text</em></p></div>`
+
+const matchingCSS = `
+li { color: #111111; }
+.item { color: #222222; }
+ul > .item.active { color: #333333; font-weight: bold; }
+#app p em, .card em { font-style: italic; }
+li:nth-child(odd of .item) { text-decoration: underline; }
+:is(p, li):last-child { margin-left: 2; }
+`
+
+func TestScanMatchesIndex(t *testing.T) {
+	root, err := ParseHTML(matchingHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules, err := ParseCSS(matchingCSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := NewRuleIndex(rules)
+	Walk(&root, func(node *Node) WalkAction {
+		indexed, scanned := index.Match(node), index.scan(node)
+		if len(indexed) != len(scanned) {
+			t.Errorf("<%s> matched %d rules with the index and %d by scanning", (*node).GetTag(), len(indexed), len(scanned))
+			return WalkContinue
+		}
+		for i := range indexed {
+			if indexed[i].rule != scanned[i].rule || indexed[i].Specificity != scanned[i].Specificity {
+				t.Errorf("<%s> match %d differs: %+v, want %+v", (*node).GetTag(), i, scanned[i], indexed[i])
+			}
+		}
+		return WalkContinue
+	})
+}
+
+func TestDeterminePropertiesMatchesApplyStylesheet(t *testing.T) {
+	rules, err := ParseCSS(matchingCSS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := ParseHTML(matchingHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStylesheet(&applied, rules)
+
+	determined, err := ParseHTML(matchingHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Parents are styled before their children, so that inherited
+	// properties are available, as ApplyStylesheet does.
+	var want []map[string]string
+	Walk(&applied, func(node *Node) WalkAction {
+		want = append(want, (*node).GetProperties())
+		return WalkContinue
+	})
+	i := 0
+	Walk(&determined, func(node *Node) WalkAction {
+		(*node).AddProperties(DetermineProperties(node, rules))
+		if got := (*node).GetProperties(); !maps.Equal(got, want[i]) {
+			t.Errorf("<%s> DetermineProperties gave %v, want %v", (*node).GetTag(), got, want[i])
+		}
+		i++
+		return WalkContinue
+	})
+
+	indexed, err := ParseHTML(matchingHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := NewRuleIndex(rules)
+	i = 0
+	Walk(&indexed, func(node *Node) WalkAction {
+		(*node).AddProperties(index.DetermineProperties(node))
+		if got := (*node).GetProperties(); !maps.Equal(got, want[i]) {
+			t.Errorf("<%s> RuleIndex.DetermineProperties gave %v, want %v", (*node).GetTag(), got, want[i])
+		}
+		i++
+		return WalkContinue
+	})
+}
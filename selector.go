@@ -1,9 +1,9 @@
 package bracelet
 
 import (
-	"errors"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 )
 
 type Selector interface {
@@ -12,104 +12,486 @@ type Selector interface {
 	String() string
 }
 
+// SelectorError describes a syntax error in a selector. Column is the 1-based
+// position, counted in characters, at which parsing failed.
+type SelectorError struct {
+	Selector string
+	Column   int
+	Message  string
+}
+
+func (e *SelectorError) Error() string {
+	return fmt.Sprintf("invalid selector %q: %s at column %d", e.Selector, e.Message, e.Column)
+}
+
+func newSelectorError(input string, pos int, format string, args ...interface{}) *SelectorError {
+	return &SelectorError{
+		Selector: input,
+		Column:   utf8.RuneCountInString(input[:pos]) + 1,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
 func parseSelector(input string) (Selector, error) {
-	input = strings.TrimSpace(input)
-	tokens := tokenizeSelector(input)
-	return parseTokens(tokens)
+	p, err := newSelectorParser(input)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseSelectorToEnd()
+}
+
+// parseSelectorList parses a comma-separated list of selectors, such as the
+// prelude of a style rule.
+func parseSelectorList(input string) ([]Selector, error) {
+	p, err := newSelectorParser(input)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseSelectorListToEnd()
+}
+
+// selectorParser is a recursive-descent parser for the CSS Selectors Level 4
+// grammar. Functional pseudo-classes are parsed by sub-parsers that share the
+// input string, so positions in errors always refer to the original selector.
+type selectorParser struct {
+	input  string
+	tokens []selectorToken
+	pos    int
+}
+
+func newSelectorParser(input string) (*selectorParser, error) {
+	tokens, err := tokenizeSelector(input)
+	if err != nil {
+		return nil, err
+	}
+	return &selectorParser{input: input, tokens: tokens}, nil
 }
 
-func tokenizeSelector(input string) []string {
-	input = strings.ReplaceAll(input, ">", " > ")
-	input = strings.ReplaceAll(input, "+", " + ")
-	input = strings.ReplaceAll(input, "~", " ~ ")
-	input = strings.ReplaceAll(input, "[", " [ ")
-	input = strings.ReplaceAll(input, "]", " ] ")
-	return strings.Fields(input)
+func (p *selectorParser) peek() selectorToken {
+	return p.tokens[p.pos]
 }
 
-func parseTokens(tokens []string) (Selector, error) {
-	if len(tokens) == 0 {
-		return nil, errors.New("empty selector")
+func (p *selectorParser) next() selectorToken {
+	token := p.tokens[p.pos]
+	if token.Type != selectorTokenTypes.EOF {
+		p.pos++
 	}
+	return token
+}
+
+func (p *selectorParser) skipWhitespace() bool {
+	skipped := false
+	for p.peek().Type == selectorTokenTypes.Whitespace {
+		p.pos++
+		skipped = true
+	}
+	return skipped
+}
+
+func (p *selectorParser) errorAt(token selectorToken, format string, args ...interface{}) error {
+	return newSelectorError(p.input, token.Pos, format, args...)
+}
+
+func (p *selectorParser) describe(token selectorToken) string {
+	if token.Type == selectorTokenTypes.EOF {
+		return "end of selector"
+	}
+	return fmt.Sprintf("%q", p.input[token.Pos:token.End])
+}
 
-	var currentSelector Selector
+// complexStep is one compound selector of a complex selector together with
+// the combinator that precedes it.
+type complexStep struct {
+	Combinator string
+	Compound   Selector
+}
 
-	for i := 0; i < len(tokens); i++ {
-		token := tokens[i]
-		switch token {
+// parseComplexSelector parses compound selectors separated by combinators.
+func (p *selectorParser) parseComplexSelector() (Selector, error) {
+	steps, err := p.parseComplexSteps()
+	if err != nil {
+		return nil, err
+	}
+	current := steps[0].Compound
+	for _, step := range steps[1:] {
+		switch step.Combinator {
 		case ">":
-			if currentSelector == nil {
-				return nil, errors.New("child selector cannot be at the start")
+			current = &childSelector{Parent: current, Child: step.Compound}
+		case "+":
+			current = &adjacentSiblingSelector{First: current, Second: step.Compound}
+		case "~":
+			current = &generalSiblingSelector{First: current, Second: step.Compound}
+		default:
+			current = &descendantSelector{Ancestor: current, Descendant: step.Compound}
+		}
+	}
+	return current, nil
+}
+
+// parseComplexSteps parses a complex selector into its compound selectors,
+// leaving the combinator of the first step empty.
+func (p *selectorParser) parseComplexSteps() ([]complexStep, error) {
+	compound, err := p.parseCompoundSelector()
+	if err != nil {
+		return nil, err
+	}
+	steps := []complexStep{{Compound: compound}}
+	for {
+		combinator, err := p.parseCombinator()
+		if err != nil {
+			return nil, err
+		}
+		if combinator == "" {
+			return steps, nil
+		}
+		compound, err := p.parseCompoundSelector()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, complexStep{Combinator: combinator, Compound: compound})
+	}
+}
+
+// parseRelativeSelector parses a complex selector with an optional leading
+// combinator, as accepted by :has(). The descendant combinator is implied
+// when none is written.
+func (p *selectorParser) parseRelativeSelector() (*relativeSelector, error) {
+	combinator := " "
+	if token := p.peek(); token.isDelim(">") || token.isDelim("+") || token.isDelim("~") {
+		combinator = p.next().Value
+		p.skipWhitespace()
+	}
+	steps, err := p.parseComplexSteps()
+	if err != nil {
+		return nil, err
+	}
+	steps[0].Combinator = combinator
+	return &relativeSelector{Steps: steps}, nil
+}
+
+// parseCombinator consumes a combinator and the whitespace around it. It
+// returns " " for the descendant combinator and "" if no combinator follows.
+func (p *selectorParser) parseCombinator() (string, error) {
+	sawWhitespace := p.skipWhitespace()
+	token := p.peek()
+	switch {
+	case token.isDelim(">"), token.isDelim("+"), token.isDelim("~"):
+		p.next()
+		p.skipWhitespace()
+		return token.Value, nil
+	case sawWhitespace && p.startsCompound(token):
+		return " ", nil
+	default:
+		return "", nil
+	}
+}
+
+func (p *selectorParser) startsCompound(token selectorToken) bool {
+	switch token.Type {
+	case selectorTokenTypes.Ident, selectorTokenTypes.Hash, selectorTokenTypes.OpenBracket, selectorTokenTypes.Colon:
+		return true
+	}
+	return token.isDelim("*") || token.isDelim(".")
+}
+
+// parseCompoundSelector parses an optional type selector followed by any
+// number of ID, class, attribute and pseudo-class selectors. Pseudo-classes
+// wrap the compound in the order they are written.
+func (p *selectorParser) parseCompoundSelector() (Selector, error) {
+	compound := &simpleSelector{}
+	var selector Selector = compound
+
+	start := p.peek()
+	switch {
+	case start.Type == selectorTokenTypes.Ident, start.isDelim("*"):
+		compound.Tag = p.next().Value
+		if token := p.peek(); token.isDelim("|") {
+			return nil, p.errorAt(token, "namespace prefixes are not supported")
+		}
+	case start.isDelim("|"):
+		return nil, p.errorAt(start, "namespace prefixes are not supported")
+	}
+
+	for {
+		token := p.peek()
+		switch {
+		case token.Type == selectorTokenTypes.Hash:
+			if compound.ID != "" {
+				return nil, p.errorAt(token, "multiple ID selectors in one compound selector")
 			}
-			i++
-			if i >= len(tokens) {
-				return nil, errors.New("missing child selector")
+			compound.ID = p.next().Value
+		case token.isDelim("."):
+			p.next()
+			name := p.next()
+			if name.Type != selectorTokenTypes.Ident {
+				return nil, p.errorAt(name, "expected class name, found %s", p.describe(name))
 			}
-			parsedChildSelector, err := parseSimpleSelector(tokens[i])
+			compound.Classes = append(compound.Classes, name.Value)
+		case token.Type == selectorTokenTypes.OpenBracket:
+			attribute, err := p.parseAttributeSelector()
 			if err != nil {
 				return nil, err
 			}
-			currentSelector = &childSelector{Parent: currentSelector, Child: parsedChildSelector}
-		case "+":
-			if currentSelector == nil {
-				return nil, errors.New("adjacent sibling selector cannot be at the start")
+			compound.AttributeSelectors = append(compound.AttributeSelectors, *attribute)
+		case token.Type == selectorTokenTypes.Colon:
+			var err error
+			selector, err = p.parsePseudoClass(selector)
+			if err != nil {
+				return nil, err
 			}
-			i++
-			if i >= len(tokens) {
-				return nil, errors.New("missing adjacent sibling selector")
+		default:
+			if token == start {
+				return nil, p.errorAt(token, "expected selector, found %s", p.describe(token))
 			}
-			siblingSelector, err := parseSimpleSelector(tokens[i])
+			return selector, nil
+		}
+	}
+}
+
+// parseAttributeSelector parses "[name]" or "[name op value modifier]", where
+// value is an identifier or a quoted string and modifier is an optional "i" or
+// "s" flag.
+func (p *selectorParser) parseAttributeSelector() (*attributeSelector, error) {
+	p.next()
+	p.skipWhitespace()
+
+	name := p.next()
+	if name.Type != selectorTokenTypes.Ident {
+		return nil, p.errorAt(name, "expected attribute name, found %s", p.describe(name))
+	}
+	selector := &attributeSelector{Name: name.Value, Operation: attributeOperation.Exists}
+	p.skipWhitespace()
+
+	token := p.next()
+	if token.Type == selectorTokenTypes.CloseBracket {
+		return selector, nil
+	}
+	if token.Type != selectorTokenTypes.Delim {
+		return nil, p.errorAt(token, "expected attribute operator or ']', found %s", p.describe(token))
+	}
+	operator := token.Value
+	if operator != "=" {
+		equals := p.next()
+		if !equals.isDelim("=") || equals.Pos != token.End {
+			return nil, p.errorAt(token, "invalid attribute operator %s", p.describe(token))
+		}
+		operator += "="
+	}
+	operation, ok := parseAttributeOperation(operator)
+	if !ok {
+		return nil, p.errorAt(token, "unsupported attribute operator %q", operator)
+	}
+	selector.Operation = operation
+	p.skipWhitespace()
+
+	value := p.next()
+	if value.Type != selectorTokenTypes.Ident && value.Type != selectorTokenTypes.String {
+		return nil, p.errorAt(value, "expected attribute value, found %s", p.describe(value))
+	}
+	selector.Value = value.Value
+	p.skipWhitespace()
+
+	if modifier := p.peek(); modifier.Type == selectorTokenTypes.Ident {
+		switch flag := strings.ToLower(modifier.Value); flag {
+		case "i", "s":
+			selector.Modifier = flag
+			p.next()
+			p.skipWhitespace()
+		default:
+			return nil, p.errorAt(modifier, "unsupported attribute modifier %s", p.describe(modifier))
+		}
+	}
+
+	if closing := p.next(); closing.Type != selectorTokenTypes.CloseBracket {
+		return nil, p.errorAt(closing, "expected ']', found %s", p.describe(closing))
+	}
+	return selector, nil
+}
+
+// parsePseudoClass parses a pseudo-class and wraps baseSelector with it.
+func (p *selectorParser) parsePseudoClass(baseSelector Selector) (Selector, error) {
+	p.next()
+	token := p.next()
+	switch token.Type {
+	case selectorTokenTypes.Ident:
+		switch strings.ToLower(token.Value) {
+		case "first-child":
+			return &firstChildSelector{baseSelector}, nil
+		case "last-child":
+			return &lastChildSelector{baseSelector}, nil
+		case "first-of-type":
+			return &firstOfTypeSelector{baseSelector}, nil
+		case "last-of-type":
+			return &lastOfTypeSelector{baseSelector}, nil
+		case "only-child":
+			return &onlyChildSelector{baseSelector}, nil
+		case "only-of-type":
+			return &onlyOfTypeSelector{baseSelector}, nil
+		case "empty":
+			return &emptySelector{baseSelector}, nil
+		case "root":
+			return &rootSelector{baseSelector}, nil
+		}
+	case selectorTokenTypes.Function:
+		arguments, err := p.parseArguments(token)
+		if err != nil {
+			return nil, err
+		}
+		switch strings.ToLower(token.Value) {
+		case "nth-child", "nth-last-child":
+			a, b, of, err := arguments.parseNthArguments()
 			if err != nil {
 				return nil, err
 			}
-			currentSelector = &adjacentSiblingSelector{First: currentSelector, Second: siblingSelector}
-		case "[":
-			attrSelector, newIndex, err := parseAttributeSelector(tokens[i:])
+			last := strings.EqualFold(token.Value, "nth-last-child")
+			return &nthChildSelector{Selector: baseSelector, A: a, B: b, Last: last, Of: of}, nil
+		case "nth-of-type", "nth-last-of-type":
+			a, b, of, err := arguments.parseNthArguments()
 			if err != nil {
 				return nil, err
 			}
-			i = newIndex
-			if currentSelector == nil {
-				currentSelector = attrSelector
-			} else {
-				currentSelector = &descendantSelector{Ancestor: currentSelector, Descendant: attrSelector}
+			if of != nil {
+				return nil, p.errorAt(token, ":%s() does not accept a selector list", token.Value)
 			}
-		default:
-			simpleSelector, err := parseSimpleSelector(token)
+			last := strings.EqualFold(token.Value, "nth-last-of-type")
+			return &nthChildSelector{Selector: baseSelector, A: a, B: b, Last: last, OfType: true}, nil
+		case "not", "is", "where":
+			selectors, err := arguments.parseSelectorListToEnd()
+			if err != nil {
+				return nil, err
+			}
+			switch strings.ToLower(token.Value) {
+			case "not":
+				return &notSelector{Base: baseSelector, Negation: selectorList(selectors)}, nil
+			case "is":
+				return &isSelector{Base: baseSelector, Arguments: selectorList(selectors)}, nil
+			default:
+				return &whereSelector{Base: baseSelector, Arguments: selectorList(selectors)}, nil
+			}
+		case "has":
+			selectors, err := arguments.parseRelativeSelectorListToEnd()
 			if err != nil {
 				return nil, err
 			}
-			if currentSelector == nil {
-				currentSelector = simpleSelector
-			} else {
-				currentSelector = &descendantSelector{Ancestor: currentSelector, Descendant: simpleSelector}
+			return &hasSelector{Base: baseSelector, Arguments: selectors}, nil
+		}
+	case selectorTokenTypes.Colon:
+		return nil, p.errorAt(token, "pseudo-elements are not supported")
+	default:
+		return nil, p.errorAt(token, "expected pseudo-class name, found %s", p.describe(token))
+	}
+	return nil, p.errorAt(token, "unsupported pseudo-class :%s", strings.TrimSuffix(p.input[token.Pos:token.End], "("))
+}
+
+// parseArguments consumes the arguments of a functional pseudo-class up to
+// the matching closing parenthesis and returns a sub-parser over them.
+func (p *selectorParser) parseArguments(function selectorToken) (*selectorParser, error) {
+	start := p.pos
+	depth := 0
+	for {
+		token := p.next()
+		switch token.Type {
+		case selectorTokenTypes.EOF:
+			return nil, p.errorAt(function, "missing ')' for :%s(", function.Value)
+		case selectorTokenTypes.Function, selectorTokenTypes.OpenParen:
+			depth++
+		case selectorTokenTypes.CloseParen:
+			if depth == 0 {
+				tokens := append([]selectorToken{}, p.tokens[start:p.pos-1]...)
+				tokens = append(tokens, selectorToken{Type: selectorTokenTypes.EOF, Pos: token.Pos, End: token.Pos})
+				return &selectorParser{input: p.input, tokens: tokens}, nil
 			}
+			depth--
 		}
 	}
+}
 
-	return currentSelector, nil
+// parseSelectorListToEnd parses the parser's entire input as a comma-separated
+// list of complex selectors.
+func (p *selectorParser) parseSelectorListToEnd() ([]Selector, error) {
+	var selectors []Selector
+	err := p.parseCommaSeparatedToEnd(func() error {
+		selector, err := p.parseComplexSelector()
+		if err == nil {
+			selectors = append(selectors, selector)
+		}
+		return err
+	})
+	return selectors, err
 }
 
-func parsePseudoSelector(baseSelector Selector, pseudo string) (Selector, error) {
-	switch {
-	case pseudo == "first-child":
-		return &firstChildSelector{baseSelector}, nil
-	case pseudo == "last-child":
-		return &lastChildSelector{baseSelector}, nil
-	case strings.HasPrefix(pseudo, "nth-child("):
-		n, err := parseNthChild(pseudo)
-		if err != nil {
-			return nil, err
+// parseRelativeSelectorListToEnd parses the parser's entire input as a
+// comma-separated list of relative selectors.
+func (p *selectorParser) parseRelativeSelectorListToEnd() ([]*relativeSelector, error) {
+	var selectors []*relativeSelector
+	err := p.parseCommaSeparatedToEnd(func() error {
+		selector, err := p.parseRelativeSelector()
+		if err == nil {
+			selectors = append(selectors, selector)
 		}
-		return &nthChildSelector{baseSelector, n}, nil
-	case strings.HasPrefix(pseudo, "not("):
-		notContent := strings.TrimSuffix(strings.TrimPrefix(pseudo, "not("), ")")
-		parsedNotSelector, err := parseSelector(notContent)
-		if err != nil {
-			return nil, fmt.Errorf("invalid :not selector: %s", err)
+		return err
+	})
+	return selectors, err
+}
+
+func (p *selectorParser) parseCommaSeparatedToEnd(parseItem func() error) error {
+	for {
+		p.skipWhitespace()
+		if err := parseItem(); err != nil {
+			return err
 		}
-		return &notSelector{Base: baseSelector, Negation: parsedNotSelector}, nil
-	default:
-		return nil, fmt.Errorf("unsupported pseudo-selector: %s", pseudo)
+		p.skipWhitespace()
+		token := p.next()
+		switch token.Type {
+		case selectorTokenTypes.EOF:
+			return nil
+		case selectorTokenTypes.Comma:
+			continue
+		default:
+			return p.errorAt(token, "unexpected %s", p.describe(token))
+		}
+	}
+}
+
+// parseSelectorToEnd parses the parser's entire input as a single complex
+// selector, ignoring surrounding whitespace.
+func (p *selectorParser) parseSelectorToEnd() (Selector, error) {
+	p.skipWhitespace()
+	selector, err := p.parseComplexSelector()
+	if err != nil {
+		return nil, err
+	}
+	p.skipWhitespace()
+	if token := p.peek(); token.Type != selectorTokenTypes.EOF {
+		return nil, p.errorAt(token, "unexpected %s", p.describe(token))
+	}
+	return selector, nil
+}
+
+// parseNthArguments parses the arguments of the :nth-* pseudo-classes: the
+// an+b microsyntax, optionally followed by "of" and a selector list.
+func (p *selectorParser) parseNthArguments() (int, int, selectorList, error) {
+	end := len(p.tokens) - 1
+	for i, token := range p.tokens {
+		if token.Type == selectorTokenTypes.Ident && strings.EqualFold(token.Value, "of") {
+			end = i
+			break
+		}
+	}
+
+	start := p.tokens[0]
+	a, b, err := parseAnPlusB(p.input[start.Pos:p.tokens[end].Pos])
+	if err != nil {
+		return 0, 0, nil, p.errorAt(start, "%v", err)
+	}
+	if end == len(p.tokens)-1 {
+		return a, b, nil, nil
+	}
+
+	of := &selectorParser{input: p.input, tokens: p.tokens[end+1:]}
+	selectors, err := of.parseSelectorListToEnd()
+	if err != nil {
+		return 0, 0, nil, err
 	}
+	return a, b, selectorList(selectors), nil
 }
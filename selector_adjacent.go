@@ -25,7 +25,7 @@ func (s *adjacentSiblingSelector) Matches(node *Node) bool {
 	}
 	siblings := (*parent).GetChildren()
 	for i, sibling := range siblings {
-		if sibling == node && i > 0 {
+		if sameNode(sibling, node) && i > 0 {
 			return s.First.Matches(siblings[i-1])
 		}
 	}
@@ -1,38 +1,44 @@
 package bracelet
 
-import (
-	"errors"
-	"fmt"
-	"strings"
-)
+import "strings"
 
 type attributeSelector struct {
 	Name      string
 	Value     string
 	Operation attributeSelectorOperationType
+	// Modifier is the optional "i" or "s" flag. With "i", values are compared
+	// case-insensitively.
+	Modifier string
 }
 type attributeSelectorOperationType int
 
 var attributeOperation = struct {
-	Exists, Exact, Contains, StartsWith, EndsWith, HyphenSeparated attributeSelectorOperationType
-}{0, 1, 2, 3, 4, 5}
+	Exists, Exact, Contains, StartsWith, EndsWith, HyphenSeparated, WhitespaceSeparated attributeSelectorOperationType
+}{0, 1, 2, 3, 4, 5, 6}
 
 func (s *attributeSelector) String() string {
+	name := escapeIdentifier(s.Name)
+	value := quoteSelectorString(s.Value)
+	if s.Modifier != "" {
+		value += " " + s.Modifier
+	}
 	switch s.Operation {
 	case attributeOperation.Exists:
-		return "[" + s.Name + "]"
+		return "[" + name + "]"
 	case attributeOperation.Exact:
-		return "[" + s.Name + "=\"" + s.Value + "\"]"
+		return "[" + name + "=" + value + "]"
 	case attributeOperation.Contains:
-		return "[" + s.Name + "*=\"" + s.Value + "\"]"
+		return "[" + name + "*=" + value + "]"
 	case attributeOperation.StartsWith:
-		return "[" + s.Name + "^=\"" + s.Value + "\"]"
+		return "[" + name + "^=" + value + "]"
 	case attributeOperation.EndsWith:
-		return "[" + s.Name + "$=\"" + s.Value + "\"]"
+		return "[" + name + "$=" + value + "]"
 	case attributeOperation.HyphenSeparated:
-		return "[" + s.Name + "|=\"" + s.Value + "\"]"
+		return "[" + name + "|=" + value + "]"
+	case attributeOperation.WhitespaceSeparated:
+		return "[" + name + "~=" + value + "]"
 	default:
-		return "[" + s.Name + "?=\"" + s.Value + "\"]"
+		return "[" + name + "?=" + value + "]"
 	}
 }
 
@@ -41,69 +47,75 @@ func (s *attributeSelector) Specificity() specificity {
 }
 
 func (s *attributeSelector) Matches(node *Node) bool {
-	value, exists := (*node).GetAttributes()[s.Name]
+	value, exists := lookupAttribute(node, s.Name)
 	if !exists {
 		return false
 	}
 
+	expected := s.Value
+	if s.Modifier == "i" {
+		value = strings.ToLower(value)
+		expected = strings.ToLower(expected)
+	}
+
 	switch s.Operation {
 	case attributeOperation.Exists:
 		return true
 	case attributeOperation.Exact:
-		return value == s.Value
+		return value == expected
 	case attributeOperation.Contains:
-		return strings.Contains(value, s.Value)
+		return expected != "" && strings.Contains(value, expected)
 	case attributeOperation.StartsWith:
-		return strings.HasPrefix(value, s.Value)
+		return expected != "" && strings.HasPrefix(value, expected)
 	case attributeOperation.EndsWith:
-		return strings.HasSuffix(value, s.Value)
+		return expected != "" && strings.HasSuffix(value, expected)
 	case attributeOperation.HyphenSeparated:
-		return value == s.Value || strings.HasPrefix(value, s.Value+"-")
+		return value == expected || strings.HasPrefix(value, expected+"-")
+	case attributeOperation.WhitespaceSeparated:
+		if expected == "" || strings.ContainsAny(expected, " \t\n\r\f") {
+			return false
+		}
+		for _, word := range strings.Fields(value) {
+			if word == expected {
+				return true
+			}
+		}
+		return false
 	default:
 		return false
 	}
 }
 
-func parseAttributeSelector(tokens []string) (*attributeSelector, int, error) {
-	if len(tokens) < 3 || tokens[0] != "[" || tokens[len(tokens)-1] != "]" {
-		return nil, 0, errors.New("invalid attribute selector")
+func parseAttributeOperation(operator string) (attributeSelectorOperationType, bool) {
+	switch operator {
+	case "=":
+		return attributeOperation.Exact, true
+	case "*=":
+		return attributeOperation.Contains, true
+	case "^=":
+		return attributeOperation.StartsWith, true
+	case "$=":
+		return attributeOperation.EndsWith, true
+	case "|=":
+		return attributeOperation.HyphenSeparated, true
+	case "~=":
+		return attributeOperation.WhitespaceSeparated, true
+	default:
+		return 0, false
 	}
+}
 
-	attrSelector := &attributeSelector{}
-	attrParts := strings.Join(tokens[1:len(tokens)-1], "")
-
-	if strings.Contains(attrParts, "=") {
-		var opStr string
-		parts := strings.SplitN(attrParts, "=", 2)
-		attrSelector.Name = strings.TrimRight(parts[0], "^$*|")
-		attrSelector.Value = strings.Trim(parts[1], "\"'")
-
-		switch parts[0][len(parts[0])-1] {
-		case '^':
-			attrSelector.Operation = attributeOperation.StartsWith
-			opStr = "^="
-		case '$':
-			attrSelector.Operation = attributeOperation.EndsWith
-			opStr = "$="
-		case '*':
-			attrSelector.Operation = attributeOperation.Contains
-			opStr = "*="
-		case '|':
-			attrSelector.Operation = attributeOperation.HyphenSeparated
-			opStr = "|="
-		default:
-			attrSelector.Operation = attributeOperation.Exact
-			opStr = "="
-		}
-
-		// Ensure the operation string is actually in the original selector
-		if !strings.Contains(attrParts, opStr) {
-			return nil, 0, fmt.Errorf("invalid attribute selector operation: %s", opStr)
+// lookupAttribute returns the value of the named attribute, matching the name
+// case-insensitively if there is no exact match.
+func lookupAttribute(node *Node, name string) (string, bool) {
+	attributes := (*node).GetAttributes()
+	if value, exists := attributes[name]; exists {
+		return value, true
+	}
+	for key, value := range attributes {
+		if strings.EqualFold(key, name) {
+			return value, true
 		}
-	} else {
-		attrSelector.Name = attrParts
-		attrSelector.Operation = attributeOperation.Exists
 	}
-
-	return attrSelector, len(tokens), nil
+	return "", false
 }
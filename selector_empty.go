@@ -0,0 +1,34 @@
+package bracelet
+
+import "strings"
+
+// emptySelector implements :empty. A node is empty if its own content and the
+// content of all its children is whitespace, and none of its children are
+// elements other than text nodes.
+type emptySelector struct {
+	Selector
+}
+
+func (s *emptySelector) Matches(node *Node) bool {
+	if !s.Selector.Matches(node) {
+		return false
+	}
+	if strings.TrimSpace((*node).GetContent()) != "" {
+		return false
+	}
+	for _, child := range (*node).GetChildren() {
+		if (*child).GetTag() != "text" || strings.TrimSpace((*child).GetContent()) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *emptySelector) Specificity() specificity {
+	spec := s.Selector.Specificity()
+	return specificity{spec[0], spec[1] + 1, spec[2]}
+}
+
+func (s *emptySelector) String() string {
+	return s.Selector.String() + ":empty"
+}
@@ -12,7 +12,7 @@ func (s *firstChildSelector) Matches(node *Node) bool {
 	if parent == nil {
 		return false
 	}
-	return (*parent).GetChildren()[0] == node
+	return sameNode((*parent).GetChildren()[0], node)
 }
 
 func (s *firstChildSelector) Specificity() specificity {
@@ -23,3 +23,32 @@ func (s *firstChildSelector) Specificity() specificity {
 func (s *firstChildSelector) String() string {
 	return s.Selector.String() + ":first-child"
 }
+
+type firstOfTypeSelector struct {
+	Selector
+}
+
+func (s *firstOfTypeSelector) Matches(node *Node) bool {
+	if !s.Selector.Matches(node) {
+		return false
+	}
+	parent := (*node).GetParent()
+	if parent == nil {
+		return false
+	}
+	for _, sibling := range (*parent).GetChildren() {
+		if tagsEqual((*sibling).GetTag(), (*node).GetTag()) {
+			return sameNode(sibling, node)
+		}
+	}
+	return false
+}
+
+func (s *firstOfTypeSelector) Specificity() specificity {
+	spec := s.Selector.Specificity()
+	return specificity{spec[0], spec[1] + 1, spec[2]}
+}
+
+func (s *firstOfTypeSelector) String() string {
+	return s.Selector.String() + ":first-of-type"
+}
@@ -0,0 +1,35 @@
+package bracelet
+
+type generalSiblingSelector struct {
+	First  Selector
+	Second Selector
+}
+
+func (s *generalSiblingSelector) String() string {
+	return s.First.String() + " ~ " + s.Second.String()
+}
+
+func (s *generalSiblingSelector) Specificity() specificity {
+	first := s.First.Specificity()
+	second := s.Second.Specificity()
+	return specificity{first[0] + second[0], first[1] + second[1], first[2] + second[2]}
+}
+
+func (s *generalSiblingSelector) Matches(node *Node) bool {
+	if !s.Second.Matches(node) {
+		return false
+	}
+	parent := (*node).GetParent()
+	if parent == nil {
+		return false
+	}
+	for _, sibling := range (*parent).GetChildren() {
+		if sameNode(sibling, node) {
+			return false
+		}
+		if s.First.Matches(sibling) {
+			return true
+		}
+	}
+	return false
+}
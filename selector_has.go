@@ -0,0 +1,137 @@
+package bracelet
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hasSelector implements :has(). It matches if any of its relative selectors,
+// anchored at the node, matches at least one other node.
+type hasSelector struct {
+	Base      Selector
+	Arguments []*relativeSelector
+}
+
+func (s *hasSelector) Matches(node *Node) bool {
+	if !s.Base.Matches(node) {
+		return false
+	}
+	for _, argument := range s.Arguments {
+		if argument.MatchesFrom(node) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *hasSelector) Specificity() specificity {
+	var max specificity
+	for _, argument := range s.Arguments {
+		if spec := argument.Specificity(); max.Less(spec) {
+			max = spec
+		}
+	}
+	baseSpec := s.Base.Specificity()
+	return specificity{baseSpec[0] + max[0], baseSpec[1] + max[1], baseSpec[2] + max[2]}
+}
+
+func (s *hasSelector) String() string {
+	arguments := make([]string, len(s.Arguments))
+	for i, argument := range s.Arguments {
+		arguments[i] = argument.String()
+	}
+	return fmt.Sprintf("%s:has(%s)", s.Base.String(), strings.Join(arguments, ", "))
+}
+
+// relativeSelector is a complex selector that starts with a combinator, such
+// as "> item.selected". Unlike other selectors it is matched left to right,
+// starting from the node it is anchored at.
+type relativeSelector struct {
+	Steps []complexStep
+}
+
+// MatchesFrom reports whether any node reached from scope by following the
+// steps of the selector matches every compound along the way.
+func (s *relativeSelector) MatchesFrom(scope *Node) bool {
+	current := []*Node{scope}
+	for _, step := range s.Steps {
+		seen := make(map[*Node]bool)
+		var next []*Node
+		for _, node := range current {
+			for _, candidate := range relatedNodes(node, step.Combinator) {
+				if !seen[candidate] && step.Compound.Matches(candidate) {
+					seen[candidate] = true
+					next = append(next, candidate)
+				}
+			}
+		}
+		if len(next) == 0 {
+			return false
+		}
+		current = next
+	}
+	return true
+}
+
+func (s *relativeSelector) Specificity() specificity {
+	var spec specificity
+	for _, step := range s.Steps {
+		stepSpec := step.Compound.Specificity()
+		spec = specificity{spec[0] + stepSpec[0], spec[1] + stepSpec[1], spec[2] + stepSpec[2]}
+	}
+	return spec
+}
+
+func (s *relativeSelector) String() string {
+	var result string
+	for i, step := range s.Steps {
+		switch {
+		case i == 0 && step.Combinator == " ":
+		case i == 0:
+			result += step.Combinator + " "
+		case step.Combinator == " ":
+			result += " "
+		default:
+			result += " " + step.Combinator + " "
+		}
+		result += step.Compound.String()
+	}
+	return result
+}
+
+// relatedNodes returns the nodes related to node by a combinator: its
+// descendants for " ", its children for ">", its next sibling for "+" and
+// all of its following siblings for "~".
+func relatedNodes(node *Node, combinator string) []*Node {
+	switch combinator {
+	case ">":
+		return (*node).GetChildren()
+	case "+", "~":
+		parent := (*node).GetParent()
+		if parent == nil {
+			return nil
+		}
+		siblings := (*parent).GetChildren()
+		for i, sibling := range siblings {
+			if !sameNode(sibling, node) {
+				continue
+			}
+			if combinator == "+" {
+				return siblings[i+1 : min(i+2, len(siblings))]
+			}
+			return siblings[i+1:]
+		}
+		return nil
+	default:
+		var descendants []*Node
+		var collect func(*Node)
+		collect = func(n *Node) {
+			for _, child := range (*n).GetChildren() {
+				descendants = append(descendants, child)
+				collect(child)
+			}
+		}
+		collect(node)
+		return descendants
+	}
+}
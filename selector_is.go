@@ -0,0 +1,47 @@
+package bracelet
+
+import "fmt"
+
+// isSelector implements :is(). Its specificity is that of the most specific
+// selector in its argument list.
+type isSelector struct {
+	Base      Selector
+	Arguments selectorList
+}
+
+func (s *isSelector) Matches(node *Node) bool {
+	return s.Base.Matches(node) && s.Arguments.Matches(node)
+}
+
+func (s *isSelector) Specificity() specificity {
+	baseSpec := s.Base.Specificity()
+	argSpec := s.Arguments.Specificity()
+	return specificity{
+		baseSpec[0] + argSpec[0],
+		baseSpec[1] + argSpec[1],
+		baseSpec[2] + argSpec[2],
+	}
+}
+
+func (s *isSelector) String() string {
+	return fmt.Sprintf("%s:is(%s)", s.Base.String(), s.Arguments.String())
+}
+
+// whereSelector implements :where(), which matches like :is() but adds no
+// specificity.
+type whereSelector struct {
+	Base      Selector
+	Arguments selectorList
+}
+
+func (s *whereSelector) Matches(node *Node) bool {
+	return s.Base.Matches(node) && s.Arguments.Matches(node)
+}
+
+func (s *whereSelector) Specificity() specificity {
+	return s.Base.Specificity()
+}
+
+func (s *whereSelector) String() string {
+	return fmt.Sprintf("%s:where(%s)", s.Base.String(), s.Arguments.String())
+}
@@ -13,7 +13,7 @@ func (s *lastChildSelector) Matches(node *Node) bool {
 		return false
 	}
 	children := (*parent).GetChildren()
-	return children[len(children)-1] == node
+	return sameNode(children[len(children)-1], node)
 }
 
 func (s *lastChildSelector) Specificity() specificity {
@@ -24,3 +24,33 @@ func (s *lastChildSelector) Specificity() specificity {
 func (s *lastChildSelector) String() string {
 	return s.Selector.String() + ":last-child"
 }
+
+type lastOfTypeSelector struct {
+	Selector
+}
+
+func (s *lastOfTypeSelector) Matches(node *Node) bool {
+	if !s.Selector.Matches(node) {
+		return false
+	}
+	parent := (*node).GetParent()
+	if parent == nil {
+		return false
+	}
+	children := (*parent).GetChildren()
+	for i := len(children) - 1; i >= 0; i-- {
+		if tagsEqual((*children[i]).GetTag(), (*node).GetTag()) {
+			return sameNode(children[i], node)
+		}
+	}
+	return false
+}
+
+func (s *lastOfTypeSelector) Specificity() specificity {
+	spec := s.Selector.Specificity()
+	return specificity{spec[0], spec[1] + 1, spec[2]}
+}
+
+func (s *lastOfTypeSelector) String() string {
+	return s.Selector.String() + ":last-of-type"
+}
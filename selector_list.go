@@ -0,0 +1,35 @@
+package bracelet
+
+import "strings"
+
+// selectorList is a comma-separated list of selectors, as accepted by
+// functional pseudo-classes. It matches if any of its selectors match and
+// takes the specificity of its most specific selector.
+type selectorList []Selector
+
+func (s selectorList) Matches(node *Node) bool {
+	for _, selector := range s {
+		if selector.Matches(node) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s selectorList) Specificity() specificity {
+	var max specificity
+	for _, selector := range s {
+		if spec := selector.Specificity(); max.Less(spec) {
+			max = spec
+		}
+	}
+	return max
+}
+
+func (s selectorList) String() string {
+	parts := make([]string, len(s))
+	for i, selector := range s {
+		parts[i] = selector.String()
+	}
+	return strings.Join(parts, ", ")
+}
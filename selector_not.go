@@ -2,9 +2,11 @@ package bracelet
 
 import "fmt"
 
+// notSelector implements :not(). Its specificity is that of the most specific
+// selector in its argument list.
 type notSelector struct {
 	Base     Selector
-	Negation Selector
+	Negation selectorList
 }
 
 func (s *notSelector) Matches(node *Node) bool {
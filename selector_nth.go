@@ -2,45 +2,148 @@ package bracelet
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+// nthChildSelector implements :nth-child() and :nth-last-child(), along with
+// their :nth-of-type() counterparts. A node matches if its 1-based position
+// among its siblings, counted from the end when Last is set, equals A*n+B for
+// some n >= 0. When Of is set, only siblings matching Of are counted and the
+// node itself must match Of. When OfType is set, only siblings with the same
+// tag are counted.
 type nthChildSelector struct {
 	Selector
-	N int
+	A, B   int
+	Last   bool
+	OfType bool
+	Of     selectorList
 }
 
 func (s *nthChildSelector) Matches(node *Node) bool {
 	if !s.Selector.Matches(node) {
 		return false
 	}
+	if s.Of != nil && !s.Of.Matches(node) {
+		return false
+	}
 	parent := (*node).GetParent()
 	if parent == nil {
 		return false
 	}
 	children := (*parent).GetChildren()
-	for i, child := range children {
-		if child == node {
-			return i+1 == s.N
+	position, found := 0, false
+	for i := range children {
+		child := children[i]
+		if s.Last {
+			child = children[len(children)-1-i]
+		}
+		if s.Of != nil && !s.Of.Matches(child) {
+			continue
+		}
+		if s.OfType && !tagsEqual((*child).GetTag(), (*node).GetTag()) {
+			continue
+		}
+		position++
+		if sameNode(child, node) {
+			found = true
+			break
 		}
 	}
-	return false
+	return found && matchesAnPlusB(s.A, s.B, position)
 }
 
 func (s *nthChildSelector) Specificity() specificity {
 	spec := s.Selector.Specificity()
-	return specificity{spec[0], spec[1] + 1, spec[2]}
+	of := s.Of.Specificity()
+	return specificity{spec[0] + of[0], spec[1] + of[1] + 1, spec[2] + of[2]}
 }
 
 func (s *nthChildSelector) String() string {
-	return fmt.Sprintf("%s:nth-child(%d)", s.Selector.String(), s.N)
+	name := "nth-child"
+	switch {
+	case s.Last && s.OfType:
+		name = "nth-last-of-type"
+	case s.OfType:
+		name = "nth-of-type"
+	case s.Last:
+		name = "nth-last-child"
+	}
+	argument := formatAnPlusB(s.A, s.B)
+	if s.Of != nil {
+		argument += " of " + s.Of.String()
+	}
+	return fmt.Sprintf("%s:%s(%s)", s.Selector.String(), name, argument)
+}
+
+// matchesAnPlusB reports whether position equals a*n+b for some n >= 0.
+func matchesAnPlusB(a, b, position int) bool {
+	if a == 0 {
+		return position == b
+	}
+	diff := position - b
+	return diff%a == 0 && diff/a >= 0
+}
+
+func formatAnPlusB(a, b int) string {
+	var result string
+	switch a {
+	case 0:
+		return strconv.Itoa(b)
+	case 1:
+		result = "n"
+	case -1:
+		result = "-n"
+	default:
+		result = strconv.Itoa(a) + "n"
+	}
+	switch {
+	case b > 0:
+		result += "+" + strconv.Itoa(b)
+	case b < 0:
+		result += strconv.Itoa(b)
+	}
+	return result
 }
 
-func parseNthChild(pseudo string) (int, error) {
-	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(pseudo, "nth-child("), ")"))
-	if err != nil {
-		return 0, fmt.Errorf("invalid nth-child value: %s", pseudo)
+var anPlusBPattern = regexp.MustCompile(`^([+-]?)(\d*)n(?:\s*([+-])\s*(\d+))?$`)
+var integerPattern = regexp.MustCompile(`^[+-]?\d+$`)
+
+// parseAnPlusB parses the an+b microsyntax used by the :nth-* pseudo-classes,
+// including the odd and even keywords.
+func parseAnPlusB(argument string) (int, int, error) {
+	value := strings.ToLower(strings.TrimSpace(argument))
+	switch {
+	case value == "odd":
+		return 2, 1, nil
+	case value == "even":
+		return 2, 0, nil
+	case integerPattern.MatchString(value):
+		b, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid an+b value: %s", argument)
+		}
+		return 0, b, nil
+	}
+
+	match := anPlusBPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid an+b value: %s", argument)
+	}
+	a := 1
+	if match[2] != "" {
+		a, _ = strconv.Atoi(match[2])
+	}
+	if match[1] == "-" {
+		a = -a
+	}
+	b := 0
+	if match[4] != "" {
+		b, _ = strconv.Atoi(match[4])
+		if match[3] == "-" {
+			b = -b
+		}
 	}
-	return n, nil
+	return a, b, nil
 }
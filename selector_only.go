@@ -0,0 +1,55 @@
+package bracelet
+
+type onlyChildSelector struct {
+	Selector
+}
+
+func (s *onlyChildSelector) Matches(node *Node) bool {
+	if !s.Selector.Matches(node) {
+		return false
+	}
+	parent := (*node).GetParent()
+	if parent == nil {
+		return false
+	}
+	children := (*parent).GetChildren()
+	return len(children) == 1 && sameNode(children[0], node)
+}
+
+func (s *onlyChildSelector) Specificity() specificity {
+	spec := s.Selector.Specificity()
+	return specificity{spec[0], spec[1] + 1, spec[2]}
+}
+
+func (s *onlyChildSelector) String() string {
+	return s.Selector.String() + ":only-child"
+}
+
+type onlyOfTypeSelector struct {
+	Selector
+}
+
+func (s *onlyOfTypeSelector) Matches(node *Node) bool {
+	if !s.Selector.Matches(node) {
+		return false
+	}
+	parent := (*node).GetParent()
+	if parent == nil {
+		return false
+	}
+	for _, sibling := range (*parent).GetChildren() {
+		if !sameNode(sibling, node) && tagsEqual((*sibling).GetTag(), (*node).GetTag()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *onlyOfTypeSelector) Specificity() specificity {
+	spec := s.Selector.Specificity()
+	return specificity{spec[0], spec[1] + 1, spec[2]}
+}
+
+func (s *onlyOfTypeSelector) String() string {
+	return s.Selector.String() + ":only-of-type"
+}
@@ -0,0 +1,27 @@
+package bracelet
+
+// rootSelector implements :root, which matches the node without a parent and
+// the root of the document a node belongs to.
+type rootSelector struct {
+	Selector
+}
+
+func (s *rootSelector) Matches(node *Node) bool {
+	if !s.Selector.Matches(node) {
+		return false
+	}
+	if (*node).GetParent() == nil {
+		return true
+	}
+	document := OwnerDocument(node)
+	return document != nil && sameNode(document.Root, node)
+}
+
+func (s *rootSelector) Specificity() specificity {
+	spec := s.Selector.Specificity()
+	return specificity{spec[0], spec[1] + 1, spec[2]}
+}
+
+func (s *rootSelector) String() string {
+	return s.Selector.String() + ":root"
+}
@@ -0,0 +1,48 @@
+package bracelet
+
+import "testing"
+
+func TestRootMatchesParsedBody(t *testing.T) {
+	root, err := ParseHTML(`<div id="app"><p id="text">Text</p></div>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node := Find(root, ":root"); node == nil || (*node).GetTag() != "body" {
+		t.Errorf(":root matched %v, want the body", node)
+	}
+	if Find(root, "body:root") == nil {
+		t.Error("body:root matched nothing")
+	}
+	if got := len(FindAll(root, ":root")); got != 1 {
+		t.Errorf(":root matched %d nodes, want 1", got)
+	}
+	if Find(root, "div:root") != nil {
+		t.Error("div:root matched a node that is not the root")
+	}
+}
+
+func TestRootVariables(t *testing.T) {
+	document := restyledDocument(t, `<div id="app"><p id="text">Text</p></div>`,
+		`:root { --accent: #ff0000; } p { color: var(--accent, #000000); }`)
+	if got := (*document.GetElementByID("text")).GetProperty("color"); got != "#ff0000" {
+		t.Errorf("color from a variable declared on :root = %q, want #ff0000", got)
+	}
+}
+
+func TestRootMatchesDocumentRoot(t *testing.T) {
+	document := NewDocument()
+	if err := document.ParseHTML(`<div id="app"><p id="text">Text</p></div>`); err != nil {
+		t.Fatal(err)
+	}
+	document.SetRoot(document.GetElementByID("app"))
+	if err := document.AddStylesheet(`:root { color: #ff0000; }`); err != nil {
+		t.Fatal(err)
+	}
+	document.Restyle()
+	if got := (*document.Root).GetProperty("color"); got != "#ff0000" {
+		t.Errorf("color of the document root = %q, want #ff0000", got)
+	}
+	if got := (*document.GetElementByID("text")).GetProperty("color"); got != "#ff0000" {
+		t.Errorf("color inherited from the document root = %q, want #ff0000", got)
+	}
+}
@@ -2,6 +2,9 @@ package bracelet
 
 import "strings"
 
+// simpleSelector is a compound selector made of an optional type selector and
+// any number of ID, class and attribute selectors. A Tag of "*" is the
+// universal selector, which matches any tag and adds no specificity.
 type simpleSelector struct {
 	Tag                string
 	ID                 string
@@ -11,11 +14,17 @@ type simpleSelector struct {
 
 func (s *simpleSelector) String() string {
 	result := s.Tag
+	if s.Tag != "*" {
+		result = escapeIdentifier(s.Tag)
+	}
 	if s.ID != "" {
-		result += "#" + s.ID
+		result += "#" + escapeIdentifier(s.ID)
 	}
 	for _, class := range s.Classes {
-		result += "." + class
+		result += "." + escapeIdentifier(class)
+	}
+	for _, attrSelector := range s.AttributeSelectors {
+		result += attrSelector.String()
 	}
 	return result
 }
@@ -27,14 +36,14 @@ func (s *simpleSelector) Specificity() specificity {
 	if s.ID != "" {
 		a = 1
 	}
-	if s.Tag != "" {
+	if s.Tag != "" && s.Tag != "*" {
 		c = 1
 	}
 	return specificity{a, b, c}
 }
 
 func (s *simpleSelector) Matches(node *Node) bool {
-	if s.Tag != "" && s.Tag != (*node).GetTag() {
+	if s.Tag != "" && s.Tag != "*" && !tagsEqual(s.Tag, (*node).GetTag()) {
 		return false
 	}
 	if s.ID != "" && s.ID != (*node).GetID() {
@@ -66,27 +75,8 @@ func (s *simpleSelector) Matches(node *Node) bool {
 	return true
 }
 
-func parseSimpleSelector(token string) (Selector, error) {
-	selector := &simpleSelector{}
-	parts := strings.Split(token, ":")
-
-	if parts[0] != "" {
-		mainParts := strings.Split(parts[0], ".")
-		if len(mainParts) > 0 && mainParts[0] != "" {
-			if mainParts[0][0] == '#' {
-				selector.ID = mainParts[0][1:]
-			} else {
-				selector.Tag = mainParts[0]
-			}
-		}
-		if len(mainParts) > 1 {
-			selector.Classes = mainParts[1:]
-		}
-	}
-
-	if len(parts) > 1 {
-		return parsePseudoSelector(selector, parts[1])
-	}
-
-	return selector, nil
+// tagsEqual compares tag names case-insensitively, since html.Parse lowercases
+// tags while custom node factories may not.
+func tagsEqual(a, b string) bool {
+	return strings.EqualFold(a, b)
 }
@@ -0,0 +1,276 @@
+package bracelet
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		selector    string
+		want        string
+		specificity specificity
+	}{
+		// Compound selectors, chained pseudo-classes, escapes and strings.
+		{`item[active]`, `item[active]`, specificity{0, 1, 1}},
+		{`li:first-child:not(.x)`, `li:first-child:not(.x)`, specificity{0, 2, 1}},
+		{`[title="a ] b"]`, `[title="a ] b"]`, specificity{0, 1, 0}},
+		{`[title='x, y' i]`, `[title="x, y" i]`, specificity{0, 1, 0}},
+		{`a\.b`, `a\.b`, specificity{0, 0, 1}},
+		{`#\31 23`, `#\31 23`, specificity{1, 0, 0}},
+		{`.a\ b`, `.a\ b`, specificity{0, 1, 0}},
+		{`  p  >  em  `, `p > em`, specificity{0, 0, 2}},
+
+		// Selector lists and combinators.
+		{`h1, h2`, `h1, h2`, specificity{0, 0, 1}},
+		{`#a, .b`, `#a, .b`, specificity{1, 0, 0}},
+		{`h1 ~ p`, `h1 ~ p`, specificity{0, 0, 2}},
+		{`a + b > c d`, `a + b > c d`, specificity{0, 0, 4}},
+
+		// The an+b microsyntax.
+		{`li:nth-child(2n+1)`, `li:nth-child(2n+1)`, specificity{0, 1, 1}},
+		{`li:nth-child(odd)`, `li:nth-child(2n+1)`, specificity{0, 1, 1}},
+		{`li:nth-child(EVEN)`, `li:nth-child(2n)`, specificity{0, 1, 1}},
+		{`li:nth-child(-n+3)`, `li:nth-child(-n+3)`, specificity{0, 1, 1}},
+		{`li:nth-child(+5)`, `li:nth-child(5)`, specificity{0, 1, 1}},
+		{`li:nth-child(n)`, `li:nth-child(n)`, specificity{0, 1, 1}},
+		{`li:nth-of-type( 3n - 1 )`, `li:nth-of-type(3n-1)`, specificity{0, 1, 1}},
+		{`li:nth-last-child(2)`, `li:nth-last-child(2)`, specificity{0, 1, 1}},
+		{`li:nth-child(2 of .x, #y)`, `li:nth-child(2 of .x, #y)`, specificity{1, 1, 1}},
+
+		// Structural pseudo-classes.
+		{`p:first-of-type`, `p:first-of-type`, specificity{0, 1, 1}},
+		{`p:last-of-type:only-of-type`, `p:last-of-type:only-of-type`, specificity{0, 2, 1}},
+		{`p:only-child`, `p:only-child`, specificity{0, 1, 1}},
+		{`p:empty`, `p:empty`, specificity{0, 1, 1}},
+		{`:root`, `:root`, specificity{0, 1, 0}},
+
+		// Logical and relational pseudo-classes take the specificity of
+		// their most specific argument, except :where which has none.
+		{`:is(a, #b)`, `:is(a, #b)`, specificity{1, 0, 0}},
+		{`:where(#a, .b) p`, `:where(#a, .b) p`, specificity{0, 0, 1}},
+		{`:not(.a, #b)`, `:not(.a, #b)`, specificity{1, 0, 0}},
+		{`div:has(> p.x, + span)`, `div:has(> p.x, + span)`, specificity{0, 1, 2}},
+		{`div:has(em)`, `div:has(em)`, specificity{0, 0, 2}},
+
+		// Attribute operators, modifiers and the universal selector.
+		{`[class~=x i]`, `[class~="x" i]`, specificity{0, 1, 0}},
+		{`tag.class[attr~=x i]`, `tag.class[attr~="x" i]`, specificity{0, 2, 1}},
+		{`[data-x|="en" s]`, `[data-x|="en" s]`, specificity{0, 1, 0}},
+		{`*`, `*`, specificity{0, 0, 0}},
+		{`*.a`, `*.a`, specificity{0, 1, 0}},
+		{`ul > *`, `ul > *`, specificity{0, 0, 1}},
+	}
+	for _, test := range tests {
+		compiled, err := CompileSelector(test.selector)
+		if err != nil {
+			t.Errorf("%s: %v", test.selector, err)
+			continue
+		}
+		if got := compiled.String(); got != test.want {
+			t.Errorf("%s: String() = %s, want %s", test.selector, got, test.want)
+		}
+		if got := compiled.Specificity(); got != test.specificity {
+			t.Errorf("%s: Specificity() = %v, want %v", test.selector, got, test.specificity)
+		}
+
+		reparsed, err := CompileSelector(compiled.String())
+		if err != nil {
+			t.Errorf("%s: parsing String() output: %v", test.selector, err)
+			continue
+		}
+		if got := reparsed.String(); got != test.want {
+			t.Errorf("%s: String() after round trip = %s, want %s", test.selector, got, test.want)
+		}
+	}
+}
+
+func TestSelectorErrors(t *testing.T) {
+	tests := []struct {
+		selector string
+		column   int
+	}{
+		{``, 1},
+		{`a >`, 4},
+		{`a[`, 3},
+		{`a[x=`, 5},
+		{`[x="unterminated`, 4},
+		{`[x y]`, 4},
+		{`[x=y z]`, 6},
+		{`:nth-child(foo)`, 12},
+		{`:nth-of-type(1 of .x)`, 2},
+		{`:bogus`, 2},
+		{`a::before`, 3},
+		{`ns|a`, 3},
+		{`#a#b`, 3},
+		{`.`, 2},
+		{`a,`, 3},
+		{`:not(`, 2},
+		{`:is(a, )`, 8},
+		// Columns count characters rather than bytes.
+		{`é ]`, 3},
+	}
+	for _, test := range tests {
+		_, err := CompileSelector(test.selector)
+		var selectorErr *SelectorError
+		if !errors.As(err, &selectorErr) {
+			t.Errorf("%q: error %v, want a *SelectorError", test.selector, err)
+			continue
+		}
+		if selectorErr.Column != test.column {
+			t.Errorf("%q: error at column %d, want %d: %v", test.selector, selectorErr.Column, test.column, err)
+		}
+	}
+}
+
+const selectorHTML = `<div id="app" class="card">
+<ul id="list">
+<li id="one" class="item first" title="a ] b">One</li>
+<li id="two" class="item" lang="en-US" data-tags="red Blue">Two</li>
+<li id="three" class="item active"></li>
+<li id="four">Four</li>
+</ul>
+<h1 id="heading">Title</h1>
+<p id="p1">Text</p>
+<p id="p2" class="a b">More</p>
+<span id="123">Escaped</span>
+</div>`
+
+func TestSelectorMatching(t *testing.T) {
+	parsed, err := ParseHTML(selectorHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := &parsed
+
+	checkSelectors(t, root, map[string]string{
+		// Compounds, attributes and escapes.
+		`li.item[title]`:          "one",
+		`[title="a ] b"]`:         "one",
+		`li.item:not(.first)`:     "two three",
+		`#\31 23`:                 "123",
+		`p.a.b`:                   "p2",
+		`[lang|=en]`:              "two",
+		`[data-tags~=blue]`:       "",
+		`[data-tags~=blue i]`:     "two",
+		`[data-tags~="red Blue"]`: "",
+		`[DATA-TAGS~=red]`:        "two",
+		`LI#four`:                 "four",
+		`ul > *`:                  "one two three four",
+		`#list *.item`:            "one two three",
+
+		// Lists and combinators.
+		`h1, #p1`:      "heading p1",
+		`h1 ~ p`:       "p1 p2",
+		`h1 + p`:       "p1",
+		`#app > ul li`: "one two three four",
+		`#one ~ .item`: "two three",
+
+		// Structural pseudo-classes.
+		`li:nth-child(odd)`:             "one three",
+		`li:nth-child(-n+2)`:            "one two",
+		`li:nth-last-child(1)`:          "four",
+		`li:nth-child(2 of .item)`:      "two",
+		`li:nth-last-child(1 of .item)`: "three",
+		`p:first-of-type`:               "p1",
+		`p:nth-last-of-type(1)`:         "p2",
+		`h1:only-of-type`:               "heading",
+		`li:empty`:                      "three",
+		`ul:only-child, li:only-child`:  "",
+		`:root`:                         "",
+		`body:root > div`:               "app",
+
+		// Logical and relational pseudo-classes.
+		`:is(h1, p):not(.a)`:       "heading p1",
+		`:where(ul) > .active`:     "three",
+		`ul:has(> .active)`:        "list",
+		`li:has(+ #four)`:          "three",
+		`div:has(span, em) > h1`:   "heading",
+		`#app > :not(p, span, h1)`: "list",
+	})
+}
+
+func TestSelectorMatchesCustomTagCase(t *testing.T) {
+	element := NewElement("DIV")
+	var node Node = &element
+	for _, selector := range []string{"div", "DIV", "Div", "*"} {
+		if !MatchesSelector(&node, selector) {
+			t.Errorf("%s did not match a node with tag DIV", selector)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	parsed, err := ParseHTML(selectorHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := byID(t, &parsed, "one")
+
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"li", "one"},
+		{"ul", "list"},
+		{".card", "app"},
+		{"div:has(h1) > ul", "list"},
+		{"p", ""},
+		{"li:nth-child(", ""},
+	}
+	for _, test := range tests {
+		got := ""
+		if node := Closest(one, test.selector); node != nil {
+			got = (*node).GetID()
+		}
+		if got != test.want {
+			t.Errorf("Closest(%s) = %q, want %q", test.selector, got, test.want)
+		}
+	}
+
+	if !MatchesSelector(one, "ul > li:first-child") {
+		t.Error("MatchesSelector did not match the first li")
+	}
+	if MatchesSelector(one, "li[") {
+		t.Error("MatchesSelector matched an invalid selector")
+	}
+	if got := MustCompileSelector(".item").Closest(one); !sameNode(got, one) {
+		t.Error("CompiledSelector.Closest did not return the node itself")
+	}
+	if got := MustCompileSelector(".item").FindAllIn(parsed); len(got) != 3 {
+		t.Errorf("FindAllIn matched %d nodes, want 3", len(got))
+	}
+}
+
+func TestMustCompileSelectorPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustCompileSelector did not panic on an invalid selector")
+		}
+	}()
+	MustCompileSelector("a[")
+}
+
+func TestParseCSSSelectorLists(t *testing.T) {
+	rules, err := ParseCSS(`h1, #p1 { color: #ff0000; } p, h2 { color: #00ff00; }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || len(rules[0].Selectors) != 2 || len(rules[1].Selectors) != 2 {
+		t.Fatalf("parsed %v, want two rules with two selectors each", rules)
+	}
+
+	parsed, err := ParseHTML(selectorHTML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ApplyStylesheet(&parsed, rules)
+	// #p1 matches the first rule with the specificity of its ID, which
+	// beats the later p selector.
+	want := map[string]string{"heading": "#ff0000", "p1": "#ff0000", "p2": "#00ff00"}
+	for id, color := range want {
+		if got := (*byID(t, &parsed, id)).GetProperty("color"); got != color {
+			t.Errorf("color of #%s = %q, want %s", id, got, color)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package bracelet
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type selectorTokenType int
+
+var selectorTokenTypes = struct {
+	EOF, Whitespace, Ident, Function, Hash, String, Number, Delim, Colon, Comma, OpenBracket, CloseBracket, OpenParen, CloseParen selectorTokenType
+}{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}
+
+// selectorToken is a single token of a selector. Pos and End are byte offsets
+// into the original selector string, which allows errors to report columns and
+// functional pseudo-classes to recover the raw text of their arguments.
+type selectorToken struct {
+	Type  selectorTokenType
+	Value string
+	Pos   int
+	End   int
+}
+
+func (t selectorToken) isDelim(delim string) bool {
+	return t.Type == selectorTokenTypes.Delim && t.Value == delim
+}
+
+type selectorTokenizer struct {
+	input string
+	pos   int
+}
+
+// tokenizeSelector splits a selector into tokens following the CSS Syntax
+// Level 3 tokenization rules that are relevant to selectors: identifiers,
+// functions, hashes, strings, numbers, whitespace and punctuation, with
+// escapes resolved in identifiers, hashes and strings.
+func tokenizeSelector(input string) ([]selectorToken, error) {
+	t := &selectorTokenizer{input: input}
+	var tokens []selectorToken
+	for {
+		token, err := t.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+		if token.Type == selectorTokenTypes.EOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (t *selectorTokenizer) next() (selectorToken, error) {
+	start := t.pos
+	token := func(tokenType selectorTokenType, value string) (selectorToken, error) {
+		return selectorToken{Type: tokenType, Value: value, Pos: start, End: t.pos}, nil
+	}
+
+	if t.pos >= len(t.input) {
+		return token(selectorTokenTypes.EOF, "")
+	}
+
+	r := t.peek(0)
+	switch {
+	case isSelectorWhitespace(r):
+		for t.pos < len(t.input) && isSelectorWhitespace(t.peek(0)) {
+			t.advance()
+		}
+		return token(selectorTokenTypes.Whitespace, " ")
+	case r == '"' || r == '\'':
+		t.advance()
+		value, err := t.consumeString(r, start)
+		if err != nil {
+			return selectorToken{}, err
+		}
+		return token(selectorTokenTypes.String, value)
+	case r == '#':
+		t.advance()
+		if isNameRune(t.peek(0)) || t.startsValidEscape(0) {
+			return token(selectorTokenTypes.Hash, t.consumeName())
+		}
+		return token(selectorTokenTypes.Delim, "#")
+	case r == ':':
+		t.advance()
+		return token(selectorTokenTypes.Colon, ":")
+	case r == ',':
+		t.advance()
+		return token(selectorTokenTypes.Comma, ",")
+	case r == '[':
+		t.advance()
+		return token(selectorTokenTypes.OpenBracket, "[")
+	case r == ']':
+		t.advance()
+		return token(selectorTokenTypes.CloseBracket, "]")
+	case r == '(':
+		t.advance()
+		return token(selectorTokenTypes.OpenParen, "(")
+	case r == ')':
+		t.advance()
+		return token(selectorTokenTypes.CloseParen, ")")
+	case isDigit(r) || (r == '.' && isDigit(t.peek(1))):
+		return token(selectorTokenTypes.Number, t.consumeNumber())
+	case t.startsIdentifier():
+		name := t.consumeName()
+		if t.peek(0) == '(' {
+			t.advance()
+			return token(selectorTokenTypes.Function, name)
+		}
+		return token(selectorTokenTypes.Ident, name)
+	default:
+		t.advance()
+		return token(selectorTokenTypes.Delim, string(r))
+	}
+}
+
+// peek returns the rune offset runes ahead of the current position, or -1 at
+// the end of the input.
+func (t *selectorTokenizer) peek(offset int) rune {
+	pos := t.pos
+	for i := 0; ; i++ {
+		if pos >= len(t.input) {
+			return -1
+		}
+		r, size := utf8.DecodeRuneInString(t.input[pos:])
+		if i == offset {
+			return r
+		}
+		pos += size
+	}
+}
+
+func (t *selectorTokenizer) advance() rune {
+	r, size := utf8.DecodeRuneInString(t.input[t.pos:])
+	t.pos += size
+	return r
+}
+
+func (t *selectorTokenizer) startsValidEscape(offset int) bool {
+	if t.peek(offset) != '\\' {
+		return false
+	}
+	next := t.peek(offset + 1)
+	return next != -1 && next != '\n' && next != '\r' && next != '\f'
+}
+
+func (t *selectorTokenizer) startsIdentifier() bool {
+	r := t.peek(0)
+	switch {
+	case r == '-':
+		next := t.peek(1)
+		return isNameStartRune(next) || next == '-' || t.startsValidEscape(1)
+	case r == '\\':
+		return t.startsValidEscape(0)
+	default:
+		return isNameStartRune(r)
+	}
+}
+
+func (t *selectorTokenizer) consumeName() string {
+	var builder strings.Builder
+	for {
+		r := t.peek(0)
+		switch {
+		case isNameRune(r):
+			builder.WriteRune(t.advance())
+		case t.startsValidEscape(0):
+			t.advance()
+			builder.WriteRune(t.consumeEscape())
+		default:
+			return builder.String()
+		}
+	}
+}
+
+func (t *selectorTokenizer) consumeNumber() string {
+	start := t.pos
+	for isDigit(t.peek(0)) {
+		t.advance()
+	}
+	if t.peek(0) == '.' && isDigit(t.peek(1)) {
+		t.advance()
+		for isDigit(t.peek(0)) {
+			t.advance()
+		}
+	}
+	number := t.input[start:t.pos]
+	if t.startsIdentifier() {
+		number += t.consumeName()
+	}
+	return number
+}
+
+// consumeEscape consumes an escape sequence, assuming the backslash has
+// already been consumed, and returns the escaped rune.
+func (t *selectorTokenizer) consumeEscape() rune {
+	if !isHexDigit(t.peek(0)) {
+		return t.advance()
+	}
+	var value rune
+	for i := 0; i < 6 && isHexDigit(t.peek(0)); i++ {
+		value = value*16 + hexValue(t.advance())
+	}
+	if isSelectorWhitespace(t.peek(0)) {
+		t.advance()
+	}
+	if value == 0 || (value >= 0xD800 && value <= 0xDFFF) || value > utf8.MaxRune {
+		return utf8.RuneError
+	}
+	return value
+}
+
+// consumeString consumes a quoted string, assuming the opening quote has
+// already been consumed.
+func (t *selectorTokenizer) consumeString(quote rune, start int) (string, error) {
+	var builder strings.Builder
+	for {
+		r := t.peek(0)
+		switch {
+		case r == -1:
+			return "", newSelectorError(t.input, start, "unterminated string")
+		case r == quote:
+			t.advance()
+			return builder.String(), nil
+		case r == '\n' || r == '\r' || r == '\f':
+			return "", newSelectorError(t.input, t.pos, "newline in string")
+		case r == '\\':
+			t.advance()
+			switch next := t.peek(0); next {
+			case -1:
+			case '\n', '\f':
+				t.advance()
+			case '\r':
+				t.advance()
+				if t.peek(0) == '\n' {
+					t.advance()
+				}
+			default:
+				builder.WriteRune(t.consumeEscape())
+			}
+		default:
+			builder.WriteRune(t.advance())
+		}
+	}
+}
+
+func isSelectorWhitespace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func hexValue(r rune) rune {
+	switch {
+	case r >= 'a':
+		return r - 'a' + 10
+	case r >= 'A':
+		return r - 'A' + 10
+	default:
+		return r - '0'
+	}
+}
+
+func isNameStartRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '_' || r >= 0x80
+}
+
+func isNameRune(r rune) bool {
+	return isNameStartRune(r) || isDigit(r) || r == '-'
+}
+
+// escapeIdentifier escapes a string so that it is tokenized as a single
+// identifier, which keeps String() output of selectors parseable.
+func escapeIdentifier(value string) string {
+	if value == "-" {
+		return "\\-"
+	}
+	var builder strings.Builder
+	for i, r := range value {
+		switch {
+		case isDigit(r) && (i == 0 || (i == 1 && value[0] == '-')):
+			builder.WriteString("\\3" + string(r) + " ")
+		case isNameRune(r):
+			builder.WriteRune(r)
+		default:
+			builder.WriteString("\\" + string(r))
+		}
+	}
+	return builder.String()
+}
+
+// quoteSelectorString wraps a value in double quotes, escaping characters that
+// would otherwise terminate or break the string.
+func quoteSelectorString(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\a `, "\r", `\d `, "\f", `\c `)
+	return `"` + replacer.Replace(value) + `"`
+}
@@ -0,0 +1,123 @@
+package bracelet
+
+// WalkAction tells Walk how to continue after visiting a node.
+type WalkAction int
+
+const (
+	// WalkContinue visits the node's children and then its following siblings.
+	WalkContinue WalkAction = iota
+	// WalkSkipChildren skips the node's children but continues with its siblings.
+	WalkSkipChildren
+	// WalkStop ends the walk immediately.
+	WalkStop
+)
+
+// Walk visits root and its descendants in document order, calling visitor for
+// each node. The returned WalkAction controls whether the node's subtree is
+// visited and whether the walk continues. Walk returns false if it was stopped.
+func Walk(root *Node, visitor func(*Node) WalkAction) bool {
+	if root == nil {
+		return true
+	}
+	switch visitor(root) {
+	case WalkStop:
+		return false
+	case WalkSkipChildren:
+		return true
+	}
+	for _, child := range (*root).GetChildren() {
+		if !Walk(child, visitor) {
+			return false
+		}
+	}
+	return true
+}
+
+// Descendants returns an iterator over the descendants of node in document
+// order, not including node itself. With Go 1.23 or later it can be used
+// directly in a range loop.
+func Descendants(node *Node) func(yield func(*Node) bool) {
+	return func(yield func(*Node) bool) {
+		Walk(node, func(n *Node) WalkAction {
+			if !sameNode(n, node) && !yield(n) {
+				return WalkStop
+			}
+			return WalkContinue
+		})
+	}
+}
+
+// Ancestors returns an iterator over the ancestors of node, starting with its
+// parent and ending with the root. With Go 1.23 or later it can be used
+// directly in a range loop.
+func Ancestors(node *Node) func(yield func(*Node) bool) {
+	return func(yield func(*Node) bool) {
+		if node == nil {
+			return
+		}
+		for parent := (*node).GetParent(); parent != nil; parent = (*parent).GetParent() {
+			if !yield(parent) {
+				return
+			}
+		}
+	}
+}
+
+// NextSibling returns the sibling immediately after node, or nil if node is
+// the last child or has no parent.
+func NextSibling(node *Node) *Node {
+	siblings, index := siblingIndex(node)
+	if index < 0 || index+1 >= len(siblings) {
+		return nil
+	}
+	return siblings[index+1]
+}
+
+// PreviousSibling returns the sibling immediately before node, or nil if node
+// is the first child or has no parent.
+func PreviousSibling(node *Node) *Node {
+	siblings, index := siblingIndex(node)
+	if index <= 0 {
+		return nil
+	}
+	return siblings[index-1]
+}
+
+// siblingIndex returns the children of node's parent and the index of node
+// among them, or -1 if node has no parent or is not one of its children.
+func siblingIndex(node *Node) ([]*Node, int) {
+	if node == nil {
+		return nil, -1
+	}
+	parent := (*node).GetParent()
+	if parent == nil {
+		return nil, -1
+	}
+	siblings := (*parent).GetChildren()
+	for i, sibling := range siblings {
+		if sameNode(sibling, node) {
+			return siblings, i
+		}
+	}
+	return siblings, -1
+}
+
+// Closest returns node or its nearest ancestor that matches the given CSS
+// selector, or nil if none does or the selector is invalid.
+func Closest(node *Node, selector string) *Node {
+	compiled, err := CompileSelector(selector)
+	if err != nil {
+		return nil
+	}
+	return compiled.Closest(node)
+}
+
+// MatchesSelector reports whether node matches the given CSS selector. It
+// returns false if the selector is invalid.
+func MatchesSelector(node *Node, selector string) bool {
+	compiled, err := CompileSelector(selector)
+	if err != nil {
+		return false
+	}
+	return node != nil && compiled.Matches(node)
+}
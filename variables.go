@@ -0,0 +1,172 @@
+package bracelet
+
+import "strings"
+
+// Custom properties, such as --accent, hold arbitrary values that other
+// declarations refer to with var(--accent) or var(--accent, fallback). They
+// cascade like any other property and are always inherited. References are
+// substituted when values are computed, so a declaration using var() is only
+// validated once its references are known. A declaration whose references
+// cannot be resolved, because a custom property is missing without a
+// fallback or custom properties refer to each other in a cycle, is invalid at
+// computed-value time and acts as unset.
+
+// isCustomProperty reports whether name is the name of a custom property.
+func isCustomProperty(name string) bool {
+	return strings.HasPrefix(name, "--")
+}
+
+// hasVariables reports whether value refers to a custom property.
+func hasVariables(value string) bool {
+	return strings.Contains(value, "var(")
+}
+
+// resolveVariables returns cascaded with the values of custom properties and
+// the references to them substituted. References are looked up in cascaded,
+// then in own, which holds the custom properties set directly on the node,
+// and finally in inherited, the properties of the parent. Values that become
+// invalid are replaced by unset, and shorthands using var() are replaced by
+// their longhands, except for longhands that are cascaded themselves.
+func (r *Registry) resolveVariables(cascaded, own, inherited map[string]string) map[string]string {
+	pending := false
+	for name, value := range cascaded {
+		if isCustomProperty(name) || hasVariables(value) {
+			pending = true
+			break
+		}
+	}
+	if !pending {
+		return cascaded
+	}
+
+	var (
+		values   = make(map[string]string)
+		resolved = make(map[string]bool)
+		cyclic   = make(map[string]bool)
+		stack    []string
+		lookup   func(name string) (string, bool)
+	)
+	lookup = func(name string) (string, bool) {
+		raw, declared := cascaded[name]
+		if !declared {
+			raw, declared = own[name]
+		}
+		if !declared {
+			value, exists := inherited[name]
+			return value, exists
+		}
+		if resolved[name] {
+			value, exists := values[name]
+			return value, exists
+		}
+		for i, pending := range stack {
+			if pending == name {
+				for _, member := range stack[i:] {
+					cyclic[member] = true
+				}
+				return "", false
+			}
+		}
+
+		stack = append(stack, name)
+		value, valid := resolveKeyword(raw, PropertyMetadata{Inherited: true}, inherited[name])
+		if valid {
+			value, valid = substituteVariables(strings.TrimSpace(value), lookup)
+		}
+		stack = stack[:len(stack)-1]
+		resolved[name] = true
+		if !valid || cyclic[name] {
+			return "", false
+		}
+		values[name] = value
+		return value, true
+	}
+
+	properties := make(map[string]string, len(cascaded))
+	for name, value := range cascaded {
+		switch {
+		case isCustomProperty(name):
+			// A custom property without a valid value takes its initial
+			// value, which is no value at all, rather than being inherited.
+			properties[name] = keywordInitial
+			if value, valid := lookup(name); valid {
+				properties[name] = value
+			}
+		case hasVariables(value):
+			substituted, valid := substituteVariables(value, lookup)
+			var declarations []Declaration
+			if valid {
+				var err error
+				declarations, err = r.resolveDeclaration(Declaration{Name: name, Value: substituted})
+				valid = err == nil
+			}
+			if !valid {
+				properties[name] = keywordUnset
+				continue
+			}
+			for _, declaration := range declarations {
+				if _, cascadedItself := cascaded[declaration.Name]; cascadedItself && declaration.Name != name {
+					continue
+				}
+				properties[declaration.Name] = declaration.Value
+			}
+		default:
+			properties[name] = value
+		}
+	}
+	return properties
+}
+
+// substituteVariables replaces every var() in value with the value lookup
+// returns for the custom property it names, or with its fallback if lookup
+// reports none. It reports false if a reference has neither, or if a var()
+// is malformed.
+func substituteVariables(value string, lookup func(name string) (string, bool)) (string, bool) {
+	var substituted strings.Builder
+	for {
+		start := strings.Index(value, "var(")
+		if start < 0 {
+			substituted.WriteString(value)
+			return substituted.String(), true
+		}
+		end := closingParenthesis(value, start+len("var("))
+		if end < 0 {
+			return "", false
+		}
+
+		name, fallback, hasFallback := strings.Cut(value[start+len("var("):end], ",")
+		name = strings.TrimSpace(name)
+		if !isCustomProperty(name) {
+			return "", false
+		}
+		replacement, found := lookup(name)
+		if !found && hasFallback {
+			replacement, found = substituteVariables(strings.TrimSpace(fallback), lookup)
+		}
+		if !found {
+			return "", false
+		}
+
+		substituted.WriteString(value[:start])
+		substituted.WriteString(replacement)
+		value = value[end+1:]
+	}
+}
+
+// closingParenthesis returns the index of the parenthesis that closes the
+// one opened just before offset, or -1 if there is none.
+func closingParenthesis(value string, offset int) int {
+	depth := 0
+	for i := offset; i < len(value); i++ {
+		switch value[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
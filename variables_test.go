@@ -0,0 +1,99 @@
+package bracelet
+
+import "testing"
+
+const variablesHTML = `<div id="theme"><div id="card"><p id="text">Text</p></div></div><p id="outside">Outside</p>`
+
+func TestVariables(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		want map[string]string
+	}{
+		{
+			name: "substitution",
+			css:  `#theme { --accent: #ff0000; } p { color: var(--accent); }`,
+			want: map[string]string{"text": "#ff0000", "outside": ""},
+		},
+		{
+			name: "fallback for a missing property",
+			css:  `p { color: var(--missing, #00ff00); }`,
+			want: map[string]string{"text": "#00ff00", "outside": "#00ff00"},
+		},
+		{
+			name: "nested fallback",
+			css:  `#card { --second: #0000ff; } p { color: var(--first, var(--second, #00ff00)); }`,
+			want: map[string]string{"text": "#0000ff", "outside": "#00ff00"},
+		},
+		{
+			name: "inherited and overridden",
+			css:  `#theme { --accent: #ff0000; } #card { --accent: #00ff00; } #theme, p { color: var(--accent, #000000); }`,
+			want: map[string]string{"theme": "#ff0000", "text": "#00ff00", "outside": "#000000"},
+		},
+		{
+			name: "variable referring to a variable",
+			css:  `#theme { --base: #ff0000; --accent: var(--base); } p { color: var(--accent); }`,
+			want: map[string]string{"text": "#ff0000"},
+		},
+		{
+			name: "missing property without fallback acts as unset",
+			css:  `#theme { color: #ff0000; } p { color: #00ff00; } #text { color: var(--missing); }`,
+			want: map[string]string{"text": "#ff0000"},
+		},
+		{
+			name: "cycle makes both properties invalid",
+			css:  `#card { --a: var(--b); --b: var(--a); } p { color: #00ff00; } #text { color: var(--a, #0000ff); }`,
+			want: map[string]string{"text": "#0000ff"},
+		},
+		{
+			name: "cycle through a fallback",
+			css:  `#card { --a: var(--b, #ff0000); --b: var(--a); } #text { color: var(--b, #0000ff); }`,
+			want: map[string]string{"text": "#0000ff"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document := restyledDocument(t, variablesHTML, test.css)
+			for id, want := range test.want {
+				if got := (*document.GetElementByID(id)).GetProperty("color"); got != want {
+					t.Errorf("color of #%s = %q, want %q", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVariablesInShorthands(t *testing.T) {
+	document := restyledDocument(t, variablesHTML, `#theme { --space: 1 2; } p { margin: var(--space); margin-top: 3; }`)
+	text := document.GetElementByID("text")
+	want := map[string]string{"margin-top": "3", "margin-right": "2", "margin-bottom": "1", "margin-left": "2"}
+	for name, value := range want {
+		if got := (*text).GetProperty(name); got != value {
+			t.Errorf("%s = %q, want %s", name, got, value)
+		}
+	}
+}
+
+func TestChangingVariableRestylesSubtree(t *testing.T) {
+	document := restyledDocument(t, variablesHTML, `#theme { --accent: #ff0000; } p { color: var(--accent, #000000); }`)
+	if len(document.dirty) != 0 || document.styleAll {
+		t.Fatalf("Restyle left %d invalidations behind", len(document.dirty))
+	}
+	text := document.GetElementByID("text")
+
+	(*document.GetElementByID("card")).SetProperty("--accent", "#00ff00")
+	document.Restyle()
+	if got := (*text).GetProperty("color"); got != "#00ff00" {
+		t.Errorf("color after setting --accent on the container = %q, want #00ff00", got)
+	}
+
+	(*document.GetElementByID("card")).RemoveProperty("--accent")
+	document.Restyle()
+	if got := (*text).GetProperty("color"); got != "#ff0000" {
+		t.Errorf("color after removing --accent from the container = %q, want #ff0000", got)
+	}
+	if got := (*document.GetElementByID("outside")).GetProperty("color"); got != "#000000" {
+		t.Errorf("color outside the container = %q, want #000000", got)
+	}
+	checkFullRestyle(t, document)
+}